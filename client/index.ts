@@ -55,7 +55,7 @@ class SessionForm {
   }
 
   initApp(session: Session) {
-    nextFlashcard(session.id)
+    reviewNextFlashcard(session.id)
       .then((flashcard: Flashcard) => {
         const app = new ReviewApp(session, flashcard)
         app.display(true);
@@ -85,6 +85,8 @@ class ReviewApp {
   ui: ReviewUI;
 
   isFirstGuess = true;
+  correction = "";
+  warning = "";
   viewCount = 0;
   correctCount = 0;
 
@@ -115,9 +117,14 @@ class ReviewApp {
     if (this.isFirstGuess) {
       this.ui.expected.textContent = "";
     } else {
-      this.ui.expected.textContent = this.flashcard.metadata.answer;
+      // The answer is hidden in multiple-choice sessions, so it comes from the submit result instead.
+      this.ui.expected.textContent = this.correction || this.flashcard.metadata.answer;
     }
 
+    this.ui.warning.textContent = this.warning;
+
+    this.displayChoices();
+
     this.hideAllAnswers();
 
     this.ui.review.style.display = "block";
@@ -152,24 +159,36 @@ class ReviewApp {
   handleSubmitClick() {
     const answer = this.ui.answer.value;
     submitAnswer(this.session.id, this.flashcard.metadata.id, answer, this.isFirstGuess)
-      .then((session: Session | null) => {
-        if (session) {
+      .then((result: SubmitResult) => {
+        if (result.isCorrect) {
           if (this.isFirstGuess) {
             this.correctCount++;
           }
           this.viewCount++;
           this.isFirstGuess = true;
-          this.session = session;
-          nextFlashcard(session.id)
+          this.correction = "";
+          this.warning = describeWarning(result);
+          this.session = result.session;
+          reviewNextFlashcard(result.session.id)
             .then((flashcard: Flashcard) => {
               this.flashcard = flashcard;
               this.display(true);
-            });
+            })
+            .catch((err: Error) => alert(err.message));
+        } else if (result.outcome === "partial") {
+          this.warning = `${result.remaining} more to go`;
+          this.display(true);
+        } else if (result.outcome === "repeated") {
+          this.warning = `Already named, ${result.remaining} more to go`;
+          this.display(true);
         } else {
           this.isFirstGuess = false;
+          this.correction = result.answer ?? "";
+          this.warning = "";
           this.display(false);
         }
-      });
+      })
+      .catch((err: Error) => alert(err.message));
   }
 
   handleAllAnswersToggleClick() {
@@ -180,6 +199,20 @@ class ReviewApp {
     }
   }
 
+  displayChoices() {
+    this.ui.choices.replaceChildren();
+    for (const choice of this.flashcard.stats.choices ?? []) {
+      const button = document.createElement("input");
+      button.type = "button";
+      button.value = choice;
+      button.addEventListener("click", () => {
+        this.ui.answer.value = choice;
+        this.ui.submit.click();
+      });
+      this.ui.choices.appendChild(button);
+    }
+  }
+
   displayAllAnswers() {
     getFlashcards(this.session.id)
       .then((flashcards: Flashcard[]) => {
@@ -215,6 +248,8 @@ class ReviewUI {
   answer: HTMLInputElement;
   submit: HTMLInputElement;
   expected: HTMLElement;
+  warning: HTMLElement;
+  choices: HTMLElement;
   allAnswersToggle: HTMLInputElement;
   allAnswers: HTMLElement;
 
@@ -230,6 +265,8 @@ class ReviewUI {
     this.answer = getHTMLInputElement("#answer");
     this.submit = getHTMLInputElement("#submit");
     this.expected = getHTMLElement("#expected");
+    this.warning = getHTMLElement("#warning");
+    this.choices = getHTMLElement("#choices");
     this.allAnswersToggle = getHTMLInputElement("#allAnswersToggle");
     this.allAnswers = getHTMLElement("#allAnswers");
   }
@@ -250,11 +287,44 @@ interface FlashcardMetadata {
   prompt: string;
   context: string;
   answer: string;
+  alternativeAnswers?: string[];
+  pattern?: string;
+  answerType?: string;
+  absoluteTolerance?: number;
+  relativeTolerance?: number;
 }
 
 interface FlashcardStats {
   viewCount: number;
   repetitions: number;
+  incorrectCount?: number;
+  incorrectAnswers?: string[];
+  choices?: string[];
+}
+
+interface SubmitResult {
+  session: Session;
+  stats: FlashcardStats;
+  isCorrect: boolean;
+  outcome: string;
+  differences?: Difference[];
+  typoTolerated?: boolean;
+  answer?: string;
+  remaining?: number;
+}
+
+interface Difference {
+  expected: string;
+  submitted: string;
+}
+
+class HTTPError extends Error {
+  status: number;
+
+  constructor(status: number, errMsg: string) {
+    super(`Request failed with status ${status}: ${errMsg}`);
+    this.status = status;
+  }
 }
 
 async function createSession(source: Record<string, FormDataEntryValue>): Promise<Session> {
@@ -289,6 +359,15 @@ async function getFlashcards(sessionId: string): Promise<Flashcard[]> {
 
 async function nextFlashcard(sessionId: string): Promise<Flashcard> {
   const response = await fetch(`sessions/${sessionId}/flashcards/next`, { method: "POST" });
+  if (!response.ok) {
+    const errMsg = await response.text();
+    throw new HTTPError(response.status, errMsg);
+  }
+  return response.json();
+}
+
+async function nextRound(sessionId: string): Promise<Session> {
+  const response = await fetch(`sessions/${sessionId}/rounds/next`, { method: "POST" });
   if (!response.ok) {
     const errMsg = await response.text();
     throw new Error(`Request failed with status ${response.status}: ${errMsg}`);
@@ -296,6 +375,20 @@ async function nextFlashcard(sessionId: string): Promise<Flashcard> {
   return response.json();
 }
 
+// reviewNextFlashcard gets the next flashcard to review. If the review limit has been
+// reached or no flashcards are due, it offers to start the next round instead.
+async function reviewNextFlashcard(sessionId: string): Promise<Flashcard> {
+  try {
+    return await nextFlashcard(sessionId);
+  } catch (err) {
+    if (!(err instanceof HTTPError) || err.status !== 404 || !confirm(`${err.message}\n\nStart the next round?`)) {
+      throw err;
+    }
+  }
+  await nextRound(sessionId);
+  return reviewNextFlashcard(sessionId);
+}
+
 async function syncFlashcards(sessionId: string, source: Record<string, FormDataEntryValue>): Promise<Session> {
   const response = await fetch(`sessions/${sessionId}/flashcards/sync`, {
     method: "POST",
@@ -308,7 +401,7 @@ async function syncFlashcards(sessionId: string, source: Record<string, FormData
   return response.json();
 }
 
-async function submitAnswer(sessionID: string, flashcardID: number, answer: string, isFirstGuess: boolean): Promise<Session | null> {
+async function submitAnswer(sessionID: string, flashcardID: number, answer: string, isFirstGuess: boolean): Promise<SubmitResult> {
   const response = await fetch(`sessions/${sessionID}/flashcards/${flashcardID}/submit`, {
     method: "POST",
     body: JSON.stringify({
@@ -316,19 +409,10 @@ async function submitAnswer(sessionID: string, flashcardID: number, answer: stri
       "isFirstGuess": isFirstGuess,
     }),
   });
-
-  // The answer was incorrect, so the session wasn't modified.
-  if (response.status == 304) {
-    return null;
-  }
-
-  // The request failed for some reason.
   if (!response.ok) {
     const errMsg = await response.text();
     throw new Error(`Request failed with status ${response.status}: ${errMsg}`);
   }
-
-  // The answer was correct and the session was modified.
   return response.json();
 }
 
@@ -355,6 +439,17 @@ function getHTMLSelectElement(selector: string): HTMLSelectElement {
   return elem as HTMLSelectElement;
 }
 
+function describeWarning(result: SubmitResult): string {
+  if (result.outcome !== "warning") {
+    return "";
+  }
+  if (result.typoTolerated) {
+    return `Typo: ${result.answer}`;
+  }
+  const differences = (result.differences ?? []).map((d: Difference) => `${d.submitted} → ${d.expected}`);
+  return `Almost: ${differences.join(", ")}`;
+}
+
 function percent(numerator: number, denominator: number): number {
   if (denominator === 0) {
     return 0;
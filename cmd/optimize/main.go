@@ -0,0 +1,53 @@
+// Package main contains code for fitting the FSRS model weights to a session's
+// review log. It reads the review log as exported by GET /sessions/:sid/log,
+// either from the file specified as the first argument or from standard input,
+// and prints the fitted weights as JSON. For time-based sessions, pass the
+// -time-based flag, so that the time between reviews is measured in days.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/lafeingcrokodil/flashcards/v2/review"
+)
+
+func main() {
+	err := run()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR\t%v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	timeBased := flag.Bool("time-based", false, "measure the time between reviews in days rather than rounds")
+	flag.Parse()
+
+	var input io.Reader = os.Stdin
+
+	if flag.NArg() > 0 {
+		f, err := os.Open(flag.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close() //nolint:errcheck
+		input = f
+	}
+
+	var log []*review.ReviewLogEntry
+	err := json.NewDecoder(input).Decode(&log)
+	if err != nil {
+		return err
+	}
+
+	weights, err := review.OptimizeFSRS(log, *timeBased, review.DefaultFSRSWeights)
+	if err != nil {
+		return err
+	}
+
+	return json.NewEncoder(os.Stdout).Encode(weights)
+}
@@ -0,0 +1,71 @@
+package review
+
+import (
+	"math/rand/v2"
+	"slices"
+)
+
+const (
+	// DefaultNumChoices is the number of choices offered in multiple-choice sessions
+	// if the session doesn't specify otherwise.
+	DefaultNumChoices = 4
+	// maxChoiceCandidates is the maximum number of flashcards with the same
+	// context, and with any context, whose answers are considered as distractors.
+	maxChoiceCandidates = 100
+)
+
+// isMultipleChoice returns true if and only if the user picks the answer to the
+// flashcard from several choices. Flashcards whose only answer is a pattern are
+// typed instead, since the pattern itself isn't a meaningful choice.
+func (f *Flashcard) isMultipleChoice(session *Session) bool {
+	return session.ReviewMode == ReviewModeMultipleChoice && !f.Metadata.isPatternOnly()
+}
+
+// choices returns the flashcard's answer and up to n-1 distractors drawn from
+// the other flashcards' answers, preferring those with the same context, in an
+// order that is random, but reproducible for a given seed. Answers that are
+// only patterns aren't offered as distractors.
+func (f *Flashcard) choices(flashcards []*Flashcard, n int, seed int64) []string {
+	rng := rand.New(rand.NewPCG(hashKey(seed, f.Metadata.ID, int64(f.Stats.ViewCount)), 0))
+
+	accepted := f.Metadata.acceptedAnswers()
+
+	// An answer counts as having the same context if any of the flashcards
+	// with that answer has the same context.
+	hasSameContext := make(map[string]bool)
+	for _, other := range flashcards {
+		answer := other.Metadata.Answer
+		if answer == "" || other.Metadata.isPatternOnly() || slices.Contains(accepted, answer) {
+			continue
+		}
+		hasSameContext[answer] = hasSameContext[answer] || other.Metadata.Context == f.Metadata.Context
+	}
+
+	var sameContext, otherContext []string
+	for answer, isSame := range hasSameContext {
+		if isSame {
+			sameContext = append(sameContext, answer)
+		} else {
+			otherContext = append(otherContext, answer)
+		}
+	}
+
+	distractors := make([]string, 0, len(hasSameContext))
+	for _, candidates := range [][]string{sameContext, otherContext} {
+		// Sort before shuffling, so that the result doesn't depend on the map order.
+		slices.Sort(candidates)
+		shuffle(rng, candidates)
+		distractors = append(distractors, candidates...)
+	}
+
+	choices := append([]string{f.Metadata.Answer}, distractors[:min(n-1, len(distractors))]...)
+	shuffle(rng, choices)
+
+	return choices
+}
+
+func shuffle(rng *rand.Rand, values []string) {
+	rng.Shuffle(len(values), func(i, j int) {
+		values[i], values[j] = values[j], values[i]
+	})
+}
@@ -0,0 +1,51 @@
+package review
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlashcard_choices(t *testing.T) {
+	flashcards := []*Flashcard{
+		{Metadata: FlashcardMetadata{ID: 1, Prompt: "P1", Context: "fruit", Answer: "apple", AlternativeAnswers: []string{"pome"}}},
+		{Metadata: FlashcardMetadata{ID: 2, Prompt: "P2", Context: "fruit", Answer: "banana"}},
+		{Metadata: FlashcardMetadata{ID: 3, Prompt: "P3", Context: "fruit", Answer: "cherry"}},
+		{Metadata: FlashcardMetadata{ID: 4, Prompt: "P4", Context: "vegetable", Answer: "carrot"}},
+		{Metadata: FlashcardMetadata{ID: 5, Prompt: "P5", Context: "vegetable", Answer: "pome"}},
+		{Metadata: FlashcardMetadata{ID: 6, Prompt: "P6", Context: "vegetable", Answer: "leek"}},
+		{Metadata: FlashcardMetadata{ID: 7, Prompt: "P7", Context: "vegetable", Answer: "banana"}},
+		{Metadata: FlashcardMetadata{ID: 8, Prompt: "P8", Context: "fruit", Answer: "(dragon)?fruit", Pattern: "(dragon)?fruit"}},
+	}
+
+	f := flashcards[0]
+
+	choices := f.choices(flashcards, 4, 1)
+	require.Len(t, choices, 4)
+	require.Contains(t, choices, "apple")
+	require.Contains(t, choices, "banana")
+	require.Contains(t, choices, "cherry")
+	require.NotContains(t, choices, "pome")
+
+	// The same seed always results in the same choices.
+	require.Equal(t, choices, f.choices(flashcards, 4, 1))
+
+	// Reversing the flashcards doesn't make a difference either.
+	reversed := slices.Clone(flashcards)
+	slices.Reverse(reversed)
+	require.Equal(t, choices, f.choices(reversed, 4, 1))
+
+	orders := make(map[string]bool)
+	for seed := range int64(10) {
+		c := f.choices(flashcards, 4, seed)
+		require.Len(t, c, 4)
+		require.Subset(t, c, []string{"apple", "banana", "cherry"}, seed)
+		orders[strings.Join(c, ",")] = true
+	}
+	require.Greater(t, len(orders), 1)
+
+	require.ElementsMatch(t, []string{"apple", "banana", "cherry", "carrot", "leek"}, f.choices(flashcards, 10, 1))
+	require.Equal(t, []string{"apple"}, f.choices(flashcards[:1], 4, 1))
+}
@@ -3,10 +3,23 @@ package review
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strconv"
 
 	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
 	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// maxPageSize is the maximum number of flashcards that are fetched at a time
+	// while looking for ones that aren't suspended or buried.
+	maxPageSize = 64
+	// maxCandidates is the maximum number of available flashcards among which
+	// an ordering other than the store's own order picks the next one.
+	maxCandidates = 100
 )
 
 // FirestoreStore stores a review session's state in a Cloud Firestore database.
@@ -39,7 +52,7 @@ func (s *FirestoreStore) DeleteFlashcards(ctx context.Context, sessionID string,
 func (s *FirestoreStore) GetFlashcard(ctx context.Context, sessionID string, flashcardID int64) (*Flashcard, error) {
 	doc, err := s.flashcardRef(sessionID, flashcardID).Get(ctx)
 	if err != nil {
-		return nil, err
+		return nil, notFound(err, "flashcard %d for session %s", flashcardID, sessionID)
 	}
 
 	var f Flashcard
@@ -75,36 +88,127 @@ func (s *FirestoreStore) SetFlashcards(ctx context.Context, sessionID string, me
 	return nil
 }
 
-// SetFlashcardStats updates a flashcard's stats.
+// SetFlashcardStats updates a flashcard's stats. Unlike a merge, an update
+// fails if the flashcard doesn't exist, so a deleted flashcard isn't recreated.
 func (s *FirestoreStore) SetFlashcardStats(ctx context.Context, sessionID string, flashcardID int64, stats *FlashcardStats) error {
 	_, err := s.flashcardRef(sessionID, flashcardID).
-		Set(ctx, &Flashcard{Stats: *stats}, firestore.Merge([]string{"stats"}))
-	return err
+		Update(ctx, []firestore.Update{{Path: "stats", Value: stats}})
+	return notFound(err, "flashcard %d for session %s", flashcardID, sessionID)
 }
 
-// NextReviewed returns a flashcard that is due to be reviewed again.
-func (s *FirestoreStore) NextReviewed(ctx context.Context, sessionID string, round int) (*Flashcard, error) {
-	iter := s.sessionRef(sessionID).
+// SetSuspended suspends or unsuspends a flashcard.
+func (s *FirestoreStore) SetSuspended(ctx context.Context, sessionID string, flashcardID int64, suspended bool) error {
+	value := any(firestore.Delete)
+	if suspended {
+		value = true
+	}
+
+	_, err := s.flashcardRef(sessionID, flashcardID).
+		Update(ctx, []firestore.Update{{Path: "stats.suspended", Value: value}})
+	return notFound(err, "flashcard %d for session %s", flashcardID, sessionID)
+}
+
+// SetBuriedUntil excludes a flashcard from reviews until the specified round (or day, for time-based sessions).
+func (s *FirestoreStore) SetBuriedUntil(ctx context.Context, sessionID string, flashcardID int64, period int) error {
+	_, err := s.flashcardRef(sessionID, flashcardID).
+		Update(ctx, []firestore.Update{{Path: "stats.buriedUntil", Value: period}})
+	return notFound(err, "flashcard %d for session %s", flashcardID, sessionID)
+}
+
+// GetChoiceCandidates returns up to the specified number of flashcards with
+// the specified context and up to the specified number of flashcards with
+// any context, whose answers can be offered as choices. The two may overlap.
+// An empty context is omitted from the stored metadata, so it can't be queried.
+func (s *FirestoreStore) GetChoiceCandidates(
+	ctx context.Context,
+	sessionID, promptContext string,
+	limit int,
+) ([]*Flashcard, error) {
+	flashcards := s.sessionRef(sessionID).Collection("flashcards")
+
+	var queries []firestore.Query
+	if promptContext != "" {
+		queries = append(queries, flashcards.Where("metadata.context", "==", promptContext).Limit(limit))
+	}
+	queries = append(queries, flashcards.Limit(limit))
+
+	var candidates []*Flashcard
+	for _, q := range queries {
+		found, err := s.lookupAllFlashcards(q.Documents(ctx))
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, found...)
+	}
+
+	return candidates, nil
+}
+
+// NextReviewed returns a flashcard that is due to be reviewed again. Suspended
+// and buried flashcards are skipped after the query, because Firestore can't
+// filter on fields that are omitted when empty, so the flashcards are fetched
+// a page at a time until an available one is found. Any ordering other than the
+// default one is applied after the query, to the first few available flashcards
+// in the default order, so that large collections aren't read in full.
+func (s *FirestoreStore) NextReviewed(ctx context.Context, sessionID string, query *ReviewQuery) (*Flashcard, error) {
+	dueField, due := "stats.nextReview", any(query.Round)
+	if query.TimeBased {
+		dueField, due = "stats.dueAt", query.Now
+	}
+
+	q := s.sessionRef(sessionID).
 		Collection("flashcards").
 		Where("stats.viewCount", ">", 0).
-		Where("stats.nextReview", "<=", round).
-		OrderBy("stats.nextReview", firestore.Asc).
+		Where(dueField, "<=", due).
+		OrderBy(dueField, firestore.Asc).
 		OrderBy("stats.viewCount", firestore.Desc).
-		OrderBy("metadata.id", firestore.Asc).
-		Limit(1).
-		Documents(ctx)
-	return s.lookupFirstFlashcard(iter)
+		OrderBy("metadata.id", firestore.Asc)
+	limit := 1
+	if query.Ordering != "" {
+		limit = maxCandidates
+	}
+
+	return s.lookupNextFlashcard(ctx, q, query, limit)
 }
 
 // NextUnreviewed returns a flashcard that has never been reviewed before.
-func (s *FirestoreStore) NextUnreviewed(ctx context.Context, sessionID string) (*Flashcard, error) {
-	iter := s.sessionRef(sessionID).
+// Suspended and buried flashcards are skipped after the query. Since new
+// flashcards are ordered by ID, which is what every ordering falls back to for
+// flashcards that haven't been reviewed yet, only the random ordering needs to
+// be applied after the query, to the first few available flashcards.
+func (s *FirestoreStore) NextUnreviewed(ctx context.Context, sessionID string, query *ReviewQuery) (*Flashcard, error) {
+	q := s.sessionRef(sessionID).
 		Collection("flashcards").
 		Where("stats.viewCount", "==", 0).
-		OrderBy("metadata.id", firestore.Asc).
-		Limit(1).
-		Documents(ctx)
-	return s.lookupFirstFlashcard(iter)
+		OrderBy("metadata.id", firestore.Asc)
+
+	limit := 1
+	if query.Ordering == OrderingRandom {
+		limit = maxCandidates
+	}
+
+	return s.lookupNextFlashcard(ctx, q, query, limit)
+}
+
+// CountSuspended returns the number of suspended flashcards.
+func (s *FirestoreStore) CountSuspended(ctx context.Context, sessionID string) (int, error) {
+	q := s.sessionRef(sessionID).
+		Collection("flashcards").
+		Where("stats.suspended", "==", true)
+
+	result, err := q.NewAggregationQuery().
+		WithCount("count").
+		Get(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	count, ok := result["count"].(*firestorepb.Value)
+	if !ok {
+		return 0, fmt.Errorf("unexpected count %v for session %s", result["count"], sessionID)
+	}
+
+	return int(count.GetIntegerValue()), nil
 }
 
 // GetSession returns the current session metadata.
@@ -114,7 +218,7 @@ func (s *FirestoreStore) GetSession(ctx context.Context, sessionID string) (*Ses
 	doc, err := s.sessionRef(sessionID).
 		Get(ctx)
 	if err != nil {
-		return nil, err
+		return nil, notFound(err, "metadata for session %s", sessionID)
 	}
 
 	err = doc.DataTo(&session)
@@ -154,6 +258,100 @@ func (s *FirestoreStore) SetSession(ctx context.Context, sessionID string, sessi
 	return err
 }
 
+// AddReviewLogEntry appends an entry to the review log, assigning it the next
+// sequence number. The entry's document ID is its sequence number, so if two
+// submissions race for the same number, only one of them can create it and the
+// other's transaction is retried.
+func (s *FirestoreStore) AddReviewLogEntry(ctx context.Context, sessionID string, entry *ReviewLogEntry) error {
+	reviewLog := s.sessionRef(sessionID).Collection("reviewLog")
+	query := reviewLog.OrderBy("seq", firestore.Desc).Limit(1)
+
+	return s.client.RunTransaction(ctx, func(_ context.Context, tx *firestore.Transaction) error {
+		docs, err := tx.Documents(query).GetAll()
+		if err != nil {
+			return err
+		}
+
+		entry.Seq = 1
+		if len(docs) > 0 {
+			var last ReviewLogEntry
+			err = docs[0].DataTo(&last)
+			if err != nil {
+				return err
+			}
+			entry.Seq = last.Seq + 1
+		}
+
+		return tx.Create(reviewLog.Doc(strconv.FormatInt(entry.Seq, 10)), entry)
+	})
+}
+
+// GetReviewLog returns all review log entries, from oldest to newest.
+func (s *FirestoreStore) GetReviewLog(ctx context.Context, sessionID string) ([]*ReviewLogEntry, error) {
+	docs, err := s.sessionRef(sessionID).
+		Collection("reviewLog").
+		OrderBy("seq", firestore.Asc).
+		Documents(ctx).
+		GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return reviewLogEntries(docs)
+}
+
+// GetReviewLogSinceLastCorrect returns the newest review log entries, from newest to oldest,
+// up to and including the newest correct one.
+func (s *FirestoreStore) GetReviewLogSinceLastCorrect(ctx context.Context, sessionID string) ([]*ReviewLogEntry, error) {
+	reviewLog := s.sessionRef(sessionID).Collection("reviewLog")
+
+	docs, err := reviewLog.
+		Where("isCorrect", "==", true).
+		OrderBy("seq", firestore.Desc).
+		Limit(1).
+		Documents(ctx).
+		GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("correct review log entries for session %s: %w", sessionID, ErrNotFound)
+	}
+
+	var lastCorrect ReviewLogEntry
+	err = docs[0].DataTo(&lastCorrect)
+	if err != nil {
+		return nil, err
+	}
+
+	docs, err = reviewLog.
+		Where("seq", ">=", lastCorrect.Seq).
+		OrderBy("seq", firestore.Desc).
+		Documents(ctx).
+		GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return reviewLogEntries(docs)
+}
+
+// DeleteReviewLogEntries deletes the review log entries with the specified sequence numbers.
+func (s *FirestoreStore) DeleteReviewLogEntries(ctx context.Context, sessionID string, seqs []int64) error {
+	writer := s.client.BulkWriter(ctx)
+	defer writer.End()
+
+	reviewLog := s.sessionRef(sessionID).Collection("reviewLog")
+
+	for _, seq := range seqs {
+		_, err := writer.Delete(reviewLog.Doc(strconv.FormatInt(seq, 10)))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (s *FirestoreStore) flashcardRef(sessionID string, flashcardID int64) *firestore.DocumentRef {
 	return s.sessionRef(sessionID).
 		Collection("flashcards").
@@ -164,17 +362,88 @@ func (s *FirestoreStore) sessionRef(sessionID string) *firestore.DocumentRef {
 	return s.client.Collection(s.collection).Doc(sessionID)
 }
 
-func (s *FirestoreStore) lookupFirstFlashcard(iter *firestore.DocumentIterator) (*Flashcard, error) {
-	flashcards, err := s.lookupAllFlashcards(iter)
+// notFound translates the error that Firestore returns for a missing document
+// into ErrNotFound, so that callers don't need to know about gRPC status codes.
+func notFound(err error, format string, args ...any) error {
+	if status.Code(err) != codes.NotFound {
+		return err
+	}
+	return fmt.Errorf(format+": %w", append(args, ErrNotFound)...)
+}
+
+// lookupNextFlashcard picks the next flashcard among the first available ones,
+// of which there are at most the specified number.
+func (s *FirestoreStore) lookupNextFlashcard(ctx context.Context, q firestore.Query, query *ReviewQuery, limit int) (*Flashcard, error) {
+	candidates, err := s.lookupAvailableFlashcards(ctx, q, query, limit)
 	if err != nil {
 		return nil, err
 	}
+	return query.pick(candidates)
+}
+
+// lookupAvailableFlashcards returns up to the specified number of flashcards
+// that aren't suspended or buried. It fetches the flashcards a page at a time,
+// starting with as many as are needed, since that's usually all it takes, and
+// doubling the page size every time some of the flashcards on the page turn out
+// to be suspended or buried.
+func (s *FirestoreStore) lookupAvailableFlashcards(
+	ctx context.Context,
+	q firestore.Query,
+	query *ReviewQuery,
+	limit int,
+) ([]*Flashcard, error) {
+	var candidates []*Flashcard
+	var last *firestore.DocumentSnapshot
+
+	pageSize := min(limit, maxPageSize)
+
+	for {
+		page := q.Limit(pageSize)
+		if last != nil {
+			page = page.StartAfter(last)
+		}
+
+		docs, err := page.Documents(ctx).GetAll()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, doc := range docs {
+			var f Flashcard
+			err = doc.DataTo(&f)
+			if err != nil {
+				return nil, err
+			}
 
-	if len(flashcards) == 0 {
-		return nil, ErrNotFound
+			if f.Stats.isAvailable(query.period()) {
+				candidates = append(candidates, &f)
+			}
+
+			if len(candidates) == limit {
+				return candidates, nil
+			}
+		}
+
+		if len(docs) < pageSize {
+			return candidates, nil
+		}
+
+		last = docs[len(docs)-1]
+		pageSize = min(2*pageSize, maxPageSize)
 	}
+}
 
-	return flashcards[0], nil
+func reviewLogEntries(docs []*firestore.DocumentSnapshot) ([]*ReviewLogEntry, error) {
+	entries := make([]*ReviewLogEntry, 0, len(docs))
+	for _, doc := range docs {
+		var entry ReviewLogEntry
+		err := doc.DataTo(&entry)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, &entry)
+	}
+	return entries, nil
 }
 
 func (s *FirestoreStore) lookupAllFlashcards(iter *firestore.DocumentIterator) ([]*Flashcard, error) {
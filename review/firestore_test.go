@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"testing"
+	"time"
 
 	"cloud.google.com/go/firestore"
 	"github.com/google/uuid"
@@ -19,6 +20,7 @@ func TestFirestoreStore(t *testing.T) {
 		IsNewRound:        true,
 		ProficiencyCounts: []int{1, 0, 1, 0, 0},
 		UnreviewedCount:   2,
+		SessionOptions:    SessionOptions{Scheduler: SM2SchedulerName},
 	}
 
 	expectedMetadata := []*FlashcardMetadata{
@@ -29,7 +31,7 @@ func TestFirestoreStore(t *testing.T) {
 	}
 
 	expectedFlashcardStats := []*FlashcardStats{
-		{ViewCount: 2, Repetitions: 2, NextReview: 3},
+		{ViewCount: 2, Repetitions: 2, NextReview: 3, EaseFactor: 2.36, Interval: 6},
 		{ViewCount: 1, Repetitions: 0, NextReview: 2},
 	}
 
@@ -90,17 +92,128 @@ func TestFirestoreStore(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, expectedFirstFlashcard, f)
 
-	unreviewed, err := store.NextUnreviewed(ctx, sessionID)
+	candidates, err := store.GetChoiceCandidates(ctx, sessionID, "C1", 2)
+	require.NoError(t, err)
+	require.Len(t, candidates, 3)
+	require.Equal(t, expectedFirstFlashcard, candidates[0])
+
+	unreviewed, err := store.NextUnreviewed(ctx, sessionID, &ReviewQuery{Round: expectedSession.Round})
 	require.NoError(t, err)
 	require.Equal(t, expectedUnreviewedFlashcard, unreviewed)
 
-	reviewed, err := store.NextReviewed(ctx, sessionID, expectedSession.Round)
+	reviewed, err := store.NextReviewed(ctx, sessionID, &ReviewQuery{Round: expectedSession.Round})
 	require.NoError(t, err)
 	require.Equal(t, expectedReviewedFlashcard, reviewed)
 
-	_, err = store.NextReviewed(ctx, sessionID, 1)
+	_, err = store.NextReviewed(ctx, sessionID, &ReviewQuery{Round: 1})
+	require.EqualError(t, err, expectedNotFoundError)
+
+	err = store.SetSuspended(ctx, sessionID, 2, true)
+	require.NoError(t, err)
+
+	_, err = store.NextReviewed(ctx, sessionID, &ReviewQuery{Round: expectedSession.Round})
+	require.EqualError(t, err, expectedNotFoundError)
+
+	suspended, err := store.CountSuspended(ctx, sessionID)
+	require.NoError(t, err)
+	require.Equal(t, 1, suspended)
+
+	err = store.SetSuspended(ctx, sessionID, 2, false)
+	require.NoError(t, err)
+
+	err = store.SetBuriedUntil(ctx, sessionID, 3, expectedSession.Round+1)
+	require.NoError(t, err)
+
+	unreviewed, err = store.NextUnreviewed(ctx, sessionID, &ReviewQuery{Round: expectedSession.Round})
+	require.NoError(t, err)
+	require.Equal(t, expectedMetadata[3], &unreviewed.Metadata)
+
+	err = store.SetBuriedUntil(ctx, sessionID, 3, 0)
+	require.NoError(t, err)
+
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	timeBasedStats := []*FlashcardStats{
+		{ViewCount: 3, Repetitions: 3, DueAt: now.Add(-2 * day)},
+		{ViewCount: 1, Repetitions: 1, DueAt: now.Add(-day)},
+	}
+
+	for i, stats := range timeBasedStats {
+		err = store.SetFlashcardStats(ctx, sessionID, int64(i+1), stats)
+		require.NoError(t, err)
+	}
+
+	reviewed, err = store.NextReviewed(ctx, sessionID, &ReviewQuery{TimeBased: true, Now: now})
+	require.NoError(t, err)
+	require.Equal(t, expectedMetadata[0], &reviewed.Metadata)
+
+	reviewed, err = store.NextReviewed(ctx, sessionID, &ReviewQuery{TimeBased: true, Now: now, Ordering: OrderingProficiency})
+	require.NoError(t, err)
+	require.Equal(t, expectedMetadata[1], &reviewed.Metadata)
+
+	_, err = store.NextReviewed(ctx, sessionID, &ReviewQuery{TimeBased: true, Now: now.Add(-3 * day)})
 	require.EqualError(t, err, expectedNotFoundError)
 
+	for i, stats := range expectedFlashcardStats {
+		err = store.SetFlashcardStats(ctx, sessionID, int64(i+1), stats)
+		require.NoError(t, err)
+	}
+
+	expectedEntry := &ReviewLogEntry{
+		FlashcardID:   2,
+		Answer:        "A2",
+		IsCorrect:     true,
+		IsFirstGuess:  true,
+		Grade:         GradeGood,
+		Round:         expectedSession.Round,
+		Time:          time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+		PreviousStats: FlashcardStats{ViewCount: 1, NextReview: 1},
+		Stats:         *expectedFlashcardStats[1],
+	}
+
+	// The entries are ordered by sequence number, even if they were made at the same time.
+	expectedLaterEntry := &ReviewLogEntry{
+		FlashcardID:   2,
+		Answer:        "A3",
+		IsFirstGuess:  true,
+		Round:         expectedSession.Round,
+		Time:          expectedEntry.Time,
+		PreviousStats: *expectedFlashcardStats[1],
+		Stats:         *expectedFlashcardStats[1],
+	}
+
+	err = store.AddReviewLogEntry(ctx, sessionID, expectedEntry)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), expectedEntry.Seq)
+
+	err = store.AddReviewLogEntry(ctx, sessionID, expectedLaterEntry)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), expectedLaterEntry.Seq)
+
+	entries, err := store.GetReviewLog(ctx, sessionID)
+	require.NoError(t, err)
+	require.Equal(t, []*ReviewLogEntry{expectedEntry, expectedLaterEntry}, entries)
+
+	entries, err = store.GetReviewLogSinceLastCorrect(ctx, sessionID)
+	require.NoError(t, err)
+	require.Equal(t, []*ReviewLogEntry{expectedLaterEntry, expectedEntry}, entries)
+
+	err = store.DeleteReviewLogEntries(ctx, sessionID, []int64{expectedEntry.Seq})
+	require.NoError(t, err)
+
+	_, err = store.GetReviewLogSinceLastCorrect(ctx, sessionID)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	err = store.DeleteReviewLogEntries(ctx, sessionID, []int64{expectedLaterEntry.Seq})
+	require.NoError(t, err)
+
+	entries, err = store.GetReviewLog(ctx, sessionID)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	err = store.SetFlashcardStats(ctx, sessionID, 10, &FlashcardStats{ViewCount: 1})
+	require.ErrorIs(t, err, ErrNotFound)
+
 	err = store.SetFlashcards(ctx, sessionID, []*FlashcardMetadata{expectedUpdatedMetadata})
 	require.NoError(t, err)
 
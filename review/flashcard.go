@@ -2,11 +2,13 @@ package review
 
 import (
 	"context"
+	"fmt"
 	"math"
+	"slices"
+	"strings"
+	"time"
 )
 
-const spacedRepetitionFactor = 2
-
 // FlashcardMetadataSource is the source of truth for flashcard metadata.
 type FlashcardMetadataSource interface {
 	// GetAll returns the metadata for all flashcards.
@@ -29,8 +31,32 @@ type FlashcardMetadata struct {
 	Prompt string `firestore:"prompt" json:"prompt"`
 	// Context helps narrow down possible answers.
 	Context string `firestore:"context,omitempty" json:"context,omitempty"`
-	// Answer is the accepted answer.
+	// Answer is the canonical accepted answer.
+	Answer string `firestore:"answer" json:"answer"`
+	// AlternativeAnswers are other accepted answers (if any).
+	AlternativeAnswers []string `firestore:"alternativeAnswers,omitempty" json:"alternativeAnswers,omitempty"`
+	// Pattern is a regular expression for further accepted answers (if any), e.g. "(the )?capital".
+	Pattern string `firestore:"pattern,omitempty" json:"pattern,omitempty"`
+	// AnswerType determines how answers are compared. Defaults to AnswerTypeText.
+	AnswerType string `firestore:"answerType,omitempty" json:"answerType,omitempty"`
+	// AbsoluteTolerance is the maximum difference between the submitted and the expected value (numeric answers only).
+	AbsoluteTolerance float64 `firestore:"absoluteTolerance,omitempty" json:"absoluteTolerance,omitempty"`
+	// RelativeTolerance is the maximum difference between the submitted and the expected value, as a fraction
+	// of the expected value (numeric answers only).
+	RelativeTolerance float64 `firestore:"relativeTolerance,omitempty" json:"relativeTolerance,omitempty"`
+	// Parts are the answers that must all be named, in any order, for the flashcard
+	// to be answered correctly (merged flashcards only).
+	Parts []AnswerPart `firestore:"parts,omitempty" json:"parts,omitempty"`
+}
+
+// AnswerPart is one of the answers to a merged flashcard.
+type AnswerPart struct {
+	// Answer is the canonical accepted answer.
 	Answer string `firestore:"answer" json:"answer"`
+	// AlternativeAnswers are other accepted answers (if any).
+	AlternativeAnswers []string `firestore:"alternativeAnswers,omitempty" json:"alternativeAnswers,omitempty"`
+	// Pattern is a regular expression for further accepted answers (if any).
+	Pattern string `firestore:"pattern,omitempty" json:"pattern,omitempty"`
 }
 
 // FlashcardStats stores mutable flashcard data like the view count.
@@ -41,14 +67,79 @@ type FlashcardStats struct {
 	Repetitions int `firestore:"repetitions,omitempty" json:"repetitions"`
 	// NextReview is the round in which the card is due to be reviewed next.
 	NextReview int `firestore:"nextReview,omitempty"`
+	// DueAt is the time at which the card is due to be reviewed next (time-based sessions only).
+	DueAt time.Time `firestore:"dueAt,omitempty" json:"dueAt,omitzero"`
+	// EaseFactor determines how quickly the interval between reviews grows (SM-2 only).
+	EaseFactor float64 `firestore:"easeFactor,omitempty" json:"easeFactor,omitempty"`
+	// Interval is the number of rounds (or days) between the last review and the next one (SM-2 and FSRS only).
+	Interval int `firestore:"interval,omitempty" json:"interval,omitempty"`
+	// Stability is the number of rounds (or days) until the probability of recall drops to 90% (FSRS only).
+	Stability float64 `firestore:"stability,omitempty" json:"stability,omitempty"`
+	// Difficulty is a measure of how hard the flashcard is to remember, from 1 to 10 (FSRS only).
+	Difficulty float64 `firestore:"difficulty,omitempty" json:"difficulty,omitempty"`
+	// LastReviewedAt is when the flashcard was last answered correctly (FSRS only).
+	LastReviewedAt time.Time `firestore:"lastReviewedAt,omitempty" json:"lastReviewedAt,omitzero"`
+	// Lapses is the number of times the flashcard was forgotten after having been learned.
+	Lapses int `firestore:"lapses,omitempty" json:"lapses,omitempty"`
+	// RelearningStep is the current relearning step, starting from 1, or 0 if the flashcard isn't being relearned.
+	RelearningStep int `firestore:"relearningStep,omitempty" json:"relearningStep,omitempty"`
+	// IsLeech is true if and only if the flashcard has lapsed too often and should probably be rewritten.
+	IsLeech bool `firestore:"isLeech,omitempty" json:"isLeech,omitempty"`
+	// Suspended is true if and only if the flashcard is excluded from reviews.
+	Suspended bool `firestore:"suspended,omitempty" json:"suspended,omitempty"`
+	// BuriedUntil is the round (or day, for time-based sessions) until which the flashcard is excluded from reviews.
+	BuriedUntil int `firestore:"buriedUntil,omitempty" json:"buriedUntil,omitempty"`
+	// IncorrectCount is the total number of incorrect answers that have been submitted.
+	IncorrectCount int `firestore:"incorrectCount,omitempty" json:"incorrectCount,omitempty"`
+	// IncorrectAnswers are the most recent incorrect answers that have been submitted since the last correct one.
+	IncorrectAnswers []string `firestore:"incorrectAnswers,omitempty" json:"incorrectAnswers,omitempty"`
+	// NamedParts are the indices of the parts that have been named since the last correct answer
+	// (merged flashcards only).
+	NamedParts []int `firestore:"namedParts,omitempty" json:"namedParts,omitempty"`
+	// Choices are the choices offered in the current review (multiple-choice sessions only).
+	Choices []string `firestore:"choices,omitempty" json:"choices,omitempty"`
+	// Revealed is true if and only if the answer has been revealed since the last review.
+	Revealed bool `firestore:"revealed,omitempty" json:"revealed,omitempty"`
 }
 
+const (
+	// maxIncorrectAnswers is the number of the most recent incorrect answers
+	// that are recorded in a flashcard's stats.
+	maxIncorrectAnswers = 10
+	// maxIncorrectAnswerLength is the number of characters after which
+	// recorded incorrect answers are truncated.
+	maxIncorrectAnswerLength = 100
+)
+
+// Grade rates how well a flashcard was recalled.
+type Grade int
+
+// Grades, from worst to best.
+const (
+	// GradeAgain means that the answer was forgotten.
+	GradeAgain Grade = iota + 1
+	// GradeHard means that the answer was recalled with serious difficulty.
+	GradeHard
+	// GradeGood means that the answer was recalled after some hesitation.
+	GradeGood
+	// GradeEasy means that the answer was recalled effortlessly.
+	GradeEasy
+)
+
 // Submission represents a user's answer to a flashcard prompt.
 type Submission struct {
 	// Answer is the submitted answer.
 	Answer string `json:"answer"`
 	// IsFirstGuess is true if and only if this is the user's first guess.
 	IsFirstGuess bool `firestore:"isFirstGuess"`
+	// Grade optionally rates how well the flashcard was recalled. If it isn't
+	// specified, a correct first guess is considered GradeGood.
+	Grade Grade `json:"grade,omitempty"`
+	// Time is when the answer was submitted.
+	Time time.Time `json:"-"`
+
+	// selfGraded is true if and only if the answer isn't checked at all.
+	selfGraded bool
 }
 
 type qualifiedPrompt struct {
@@ -56,30 +147,342 @@ type qualifiedPrompt struct {
 	context string
 }
 
-// Submit updates the flashcard's stats after being reviewed.
-// Returns true if and only if the answer is correct.
-func (f *Flashcard) Submit(submission *Submission, round int) bool {
-	if submission.Answer != f.Metadata.Answer {
-		return false
+// Submit updates the flashcard's stats after being reviewed, using the
+// scheduler to decide how many rounds (or days, for time-based sessions)
+// until the flashcard should be reviewed next, unless the flashcard is going
+// through the session's relearning steps. A flashcard whose lapses reach the
+// session's leech threshold is marked as a leech. A typed answer submitted after
+// the answer was revealed doesn't count as a first guess, and neither does an
+// answer that was accepted with a warning, unless the session says otherwise.
+// Incorrect answers are recorded, but otherwise leave the stats unchanged, and
+// so do the individual answers to a merged flashcard, except for the last one.
+// Returns how the answer compares to the expected answer.
+func (f *Flashcard) Submit(submission *Submission, session *Session, scheduler Scheduler) *Match {
+	match := &Match{Outcome: OutcomeCorrect}
+	if !submission.selfGraded {
+		match = f.match(submission.Answer, session)
+	}
+
+	switch match.Outcome {
+	case OutcomeWrong:
+		f.Stats.IncorrectCount++
+		f.Stats.IncorrectAnswers = recordIncorrectAnswer(f.Stats.IncorrectAnswers, submission.Answer)
+		return match
+	case OutcomePartial:
+		f.Stats.NamedParts = append(slices.Clip(f.Stats.NamedParts), match.part)
+		return match
+	case OutcomeRepeated:
+		return match
 	}
 
-	f.Stats.ViewCount++
+	submission = submission.counted(&f.Stats, match, session)
 
-	if submission.IsFirstGuess {
-		f.Stats.NextReview = round + interval(f.Stats.Repetitions)
-		f.Stats.Repetitions++
+	stats, next := f.schedule(submission, session, scheduler)
+	stats.ViewCount++
+	stats.Revealed = false
+	stats.IncorrectAnswers = nil
+	stats.NamedParts = nil
+	stats.Choices = nil
+
+	if session.LeechThreshold > 0 && stats.Lapses >= session.LeechThreshold && !stats.IsLeech {
+		stats.IsLeech = true
+		stats.Suspended = session.SuspendLeeches
+	}
+
+	if session.TimeBased {
+		stats.DueAt = submission.Time.Add(time.Duration(next) * day)
 	} else {
-		f.Stats.NextReview = round + 1
-		f.Stats.Repetitions = 0
+		stats.NextReview = session.Round + next
 	}
 
-	return true
+	f.Stats = stats
+
+	return match
 }
 
-func (m *FlashcardMetadata) qualifiedPrompt() qualifiedPrompt {
-	return qualifiedPrompt{prompt: m.Prompt, context: m.Context}
+// recordIncorrectAnswer appends the answer, truncated if necessary, to the
+// incorrect answers, keeping only the most recent ones.
+func recordIncorrectAnswer(answers []string, answer string) []string {
+	if runes := []rune(answer); len(runes) > maxIncorrectAnswerLength {
+		answer = string(runes[:maxIncorrectAnswerLength]) + "…"
+	}
+
+	answers = append(slices.Clip(answers), answer)
+
+	return answers[max(len(answers)-maxIncorrectAnswers, 0):]
 }
 
-func interval(repetitions int) int {
-	return int(math.Round(math.Pow(spacedRepetitionFactor, float64(repetitions))))
+// schedule returns the updated stats and the number of rounds (or days) until
+// the next review. A lapse starts the relearning steps, during which the
+// scheduler isn't consulted. Once the last step has been passed, the flashcard
+// graduates back to the scheduler's intervals.
+func (f *Flashcard) schedule(submission *Submission, session *Session, scheduler Scheduler) (FlashcardStats, int) {
+	steps := session.RelearningSteps
+	isForgotten := submission.grade() == GradeAgain
+	isLapse := isForgotten && f.Stats.Repetitions > 0
+
+	if len(steps) == 0 || (f.Stats.RelearningStep == 0 && !isLapse) {
+		stats, next := f.fuzzedSchedule(session, f.Stats, submission, scheduler)
+		if isLapse {
+			stats.Lapses++
+		}
+		return stats, next
+	}
+
+	if f.Stats.RelearningStep == 0 {
+		stats, _ := scheduler.Schedule(session, f.Stats, submission)
+		stats.Lapses++
+		return relearn(stats, submission, 1, steps)
+	}
+
+	stats := f.Stats
+
+	switch {
+	case isForgotten:
+		return relearn(stats, submission, 1, steps)
+	case stats.RelearningStep < len(steps):
+		return relearn(stats, submission, stats.RelearningStep+1, steps)
+	default:
+		stats.RelearningStep = 0
+		return f.fuzzedSchedule(session, stats, submission, scheduler)
+	}
+}
+
+// relearn returns the updated stats and the number of rounds (or days) until
+// the next review for the specified relearning step. Schedulers that keep track
+// of the interval rely on it to work out how long ago the flashcard was last
+// reviewed, so it's set to the length of the step.
+func relearn(stats FlashcardStats, submission *Submission, step int, steps []int) (FlashcardStats, int) {
+	stats.RelearningStep = step
+	next := steps[step-1]
+
+	if stats.Interval != 0 || stats.Stability != 0 {
+		stats.Interval = next
+		stats.LastReviewedAt = submission.Time
+	}
+
+	return stats, next
+}
+
+// fuzzedSchedule consults the scheduler and, if the session enables interval
+// fuzz, randomly adjusts the interval so that flashcards that were learned
+// together don't keep coming due together. The fuzz is seeded per flashcard and
+// review, so it's reproducible. It isn't applied to the Leitner system, whose
+// intervals are aligned with the box cadences.
+func (f *Flashcard) fuzzedSchedule(
+	session *Session,
+	stats FlashcardStats,
+	submission *Submission,
+	scheduler Scheduler,
+) (FlashcardStats, int) {
+	stats, next := scheduler.Schedule(session, stats, submission)
+	if session.IntervalFuzz == 0 || scheduler.Name() == LeitnerSchedulerName {
+		return stats, next
+	}
+
+	fuzzed := fuzzInterval(next, session.IntervalFuzz, hashKey(session.Seed, f.Metadata.ID, int64(f.Stats.ViewCount)))
+
+	// Schedulers that keep track of the interval rely on it to work out how
+	// much time has elapsed by the next review.
+	if stats.Interval == next {
+		stats.Interval = fuzzed
+	}
+
+	return stats, fuzzed
+}
+
+// counted returns the submission as it counts towards the flashcard's stats:
+// a typed answer submitted after the answer was revealed isn't a first guess,
+// and neither is one that was accepted with a warning, unless the session
+// counts warnings as first guesses. A tolerated typo doesn't mean that the
+// answer was forgotten, though, so it's considered GradeHard instead, unless
+// the submission specifies a grade.
+func (s *Submission) counted(stats *FlashcardStats, match *Match, session *Session) *Submission {
+	if s.selfGraded {
+		return s
+	}
+
+	corrected := *s
+
+	switch {
+	case stats.Revealed:
+		corrected.IsFirstGuess = false
+	case match.Outcome != OutcomeWarning || session.WarningsCountAsFirstGuess:
+		return s
+	case match.TypoTolerated:
+		if corrected.Grade == 0 {
+			corrected.Grade = GradeHard
+		}
+	default:
+		corrected.IsFirstGuess = false
+	}
+
+	return &corrected
+}
+
+// grade returns how well the flashcard was recalled. Anything other than the
+// first guess is always considered GradeAgain.
+func (s *Submission) grade() Grade {
+	switch {
+	case !s.IsFirstGuess:
+		return GradeAgain
+	case s.Grade == 0:
+		return GradeGood
+	default:
+		return s.Grade
+	}
+}
+
+// isAvailable returns true if and only if the flashcard can be reviewed in the
+// specified round (or day, for time-based sessions).
+func (s *FlashcardStats) isAvailable(period int) bool {
+	return !s.Suspended && s.BuriedUntil <= period
+}
+
+// isValid returns true if and only if the grade is one of the defined grades.
+func (g Grade) isValid() bool {
+	return g >= GradeAgain && g <= GradeEasy
+}
+
+// logged returns a copy of the stats without the state of the review in
+// progress, which is only relevant until the flashcard has been answered
+// correctly. Undoing a submission restores these stats, so the flashcard is
+// then reviewed afresh: the choices are drawn again (reproducibly, since they
+// depend on the seed) and the answer has to be revealed again.
+func (s FlashcardStats) logged() FlashcardStats {
+	s.Revealed = false
+	s.IncorrectAnswers = nil
+	s.NamedParts = nil
+	s.Choices = nil
+	return s
+}
+
+// visible returns the flashcard as it may be shown to the user during the
+// session: in reveal mode, the answer is omitted unless it has already been
+// revealed, and in multiple-choice mode, it's always omitted.
+func (f *Flashcard) visible(session *Session) *Flashcard {
+	switch {
+	case session.ReviewMode == ReviewModeReveal && !f.Stats.Revealed:
+		return f.withoutAnswer()
+	case f.isMultipleChoice(session):
+		return f.withoutAnswer()
+	default:
+		return f
+	}
+}
+
+// withoutAnswer returns a copy of the flashcard that doesn't give away the answer.
+func (f *Flashcard) withoutAnswer() *Flashcard {
+	hidden := *f
+	hidden.Metadata.Answer = ""
+	hidden.Metadata.AlternativeAnswers = nil
+	hidden.Metadata.Pattern = ""
+	hidden.Metadata.Parts = nil
+	return &hidden
+}
+
+// validate returns an error if the answer can't be checked, e.g. because its
+// pattern isn't a valid regular expression.
+func (m *FlashcardMetadata) validate() error {
+	if m.Pattern != "" {
+		_, err := compilePattern(m.Pattern)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, part := range m.Parts {
+		if part.Pattern != "" {
+			_, err := compilePattern(part.Pattern)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	switch m.AnswerType {
+	case "", AnswerTypeText:
+	case AnswerTypeNumeric:
+		_, err := parseQuantity(m.Answer)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("answer type %s: %w", m.AnswerType, ErrUnknownAnswerType)
+	}
+
+	if !isValidTolerance(m.AbsoluteTolerance) || !isValidTolerance(m.RelativeTolerance) {
+		return fmt.Errorf("tolerances %v and %v: %w", m.AbsoluteTolerance, m.RelativeTolerance, ErrInvalidNumber)
+	}
+
+	return nil
+}
+
+// isValidTolerance returns true if and only if the tolerance is a finite,
+// non-negative number.
+func isValidTolerance(tolerance float64) bool {
+	return !math.IsNaN(tolerance) && !math.IsInf(tolerance, 0) && tolerance >= 0
+}
+
+// isPatternOnly returns true if and only if the canonical answer is the pattern
+// itself, because no other answers were given.
+func (m *FlashcardMetadata) isPatternOnly() bool {
+	return m.Pattern != "" && m.Answer == m.Pattern
+}
+
+// isNumeric returns true if and only if the answers are compared numerically.
+func (m *FlashcardMetadata) isNumeric() bool {
+	return m.AnswerType == AnswerTypeNumeric
+}
+
+// hasSameAnswers returns true if and only if the same answers are accepted,
+// in the same way. Tolerances are only compared for numeric answers.
+func (m *FlashcardMetadata) hasSameAnswers(other *FlashcardMetadata) bool {
+	return slices.Equal(m.acceptedAnswers(), other.acceptedAnswers()) &&
+		m.Pattern == other.Pattern &&
+		m.isNumeric() == other.isNumeric() &&
+		(!m.isNumeric() ||
+			m.AbsoluteTolerance == other.AbsoluteTolerance && m.RelativeTolerance == other.RelativeTolerance)
+}
+
+// acceptedAnswers returns all accepted answers, starting with the canonical one.
+func (m *FlashcardMetadata) acceptedAnswers() []string {
+	return append([]string{m.Answer}, m.AlternativeAnswers...)
+}
+
+// describeAnswers lists the accepted answers, the pattern (if any) and the
+// tolerances of numeric answers, e.g. for error messages.
+func (m *FlashcardMetadata) describeAnswers() string {
+	answers := m.acceptedAnswers()
+	if m.Pattern != "" {
+		answers = append(answers, "pattern "+m.Pattern)
+	}
+	if m.isNumeric() {
+		answers = append(answers, fmt.Sprintf("numeric with tolerances %v and %v", m.AbsoluteTolerance, m.RelativeTolerance))
+	}
+	return strings.Join(answers, " | ")
+}
+
+// equal returns true if and only if the metadata is identical.
+func (m *FlashcardMetadata) equal(other *FlashcardMetadata) bool {
+	return m.ID == other.ID &&
+		m.Prompt == other.Prompt &&
+		m.Context == other.Context &&
+		slices.Equal(m.acceptedAnswers(), other.acceptedAnswers()) &&
+		m.Pattern == other.Pattern &&
+		m.AnswerType == other.AnswerType &&
+		m.AbsoluteTolerance == other.AbsoluteTolerance &&
+		m.RelativeTolerance == other.RelativeTolerance &&
+		slices.EqualFunc(m.Parts, other.Parts, func(a, b AnswerPart) bool {
+			return slices.Equal(a.acceptedAnswers(), b.acceptedAnswers()) && a.Pattern == b.Pattern
+		})
+}
+
+// acceptedAnswers returns all accepted answers, starting with the canonical one.
+func (p *AnswerPart) acceptedAnswers() []string {
+	return append([]string{p.Answer}, p.AlternativeAnswers...)
+}
+
+func (m *FlashcardMetadata) qualifiedPrompt() qualifiedPrompt {
+	return qualifiedPrompt{prompt: m.Prompt, context: m.Context}
 }
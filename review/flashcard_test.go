@@ -3,6 +3,7 @@ package review
 import (
 	"fmt"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -70,9 +71,11 @@ func TestFlashcard_Submit(t *testing.T) {
 			expectedState: &Flashcard{
 				Metadata: flashcardMetadata(1),
 				Stats: FlashcardStats{
-					ViewCount:   3,
-					Repetitions: 3,
-					NextReview:  7,
+					ViewCount:        3,
+					Repetitions:      3,
+					NextReview:       7,
+					IncorrectCount:   1,
+					IncorrectAnswers: []string{"2"},
 				},
 			},
 		},
@@ -84,21 +87,231 @@ func TestFlashcard_Submit(t *testing.T) {
 			expectedState: &Flashcard{
 				Metadata: flashcardMetadata(1),
 				Stats: FlashcardStats{
-					ViewCount:   4,
-					Repetitions: 0,
-					NextReview:  8,
+					ViewCount:      4,
+					Repetitions:    0,
+					NextReview:     8,
+					Lapses:         1,
+					IncorrectCount: 1,
 				},
 			},
 		},
 	}
 
 	for _, update := range updates {
-		ok := f.Submit(update.submission, update.round)
-		require.Equal(t, update.expectedOK, ok, update.id)
+		match := f.Submit(update.submission, &Session{Round: update.round}, NewDoublingScheduler())
+		require.Equal(t, update.expectedOK, match.IsCorrect(), update.id)
 		require.Equal(t, update.expectedState, f, update.id)
 	}
 }
 
+func TestFlashcard_Submit_relearning(t *testing.T) {
+	f := &Flashcard{
+		Metadata: flashcardMetadata(1),
+		Stats:    FlashcardStats{ViewCount: 3, Repetitions: 3, NextReview: 7},
+	}
+
+	session := &Session{SessionOptions: SessionOptions{RelearningSteps: []int{0, 1, 3}}}
+
+	updates := []struct {
+		id            string
+		submission    *Submission
+		round         int
+		expectedStats FlashcardStats
+	}{
+		{
+			id:            "Lapse",
+			submission:    &Submission{Answer: "1", IsFirstGuess: false},
+			round:         7,
+			expectedStats: FlashcardStats{ViewCount: 4, NextReview: 7, Lapses: 1, RelearningStep: 1},
+		},
+		{
+			id:            "First relearning step passed",
+			submission:    &Submission{Answer: "1", IsFirstGuess: true},
+			round:         7,
+			expectedStats: FlashcardStats{ViewCount: 5, NextReview: 8, Lapses: 1, RelearningStep: 2},
+		},
+		{
+			id:            "Second relearning step failed",
+			submission:    &Submission{Answer: "1", IsFirstGuess: false},
+			round:         8,
+			expectedStats: FlashcardStats{ViewCount: 6, NextReview: 8, Lapses: 1, RelearningStep: 1},
+		},
+		{
+			id:            "First relearning step passed again",
+			submission:    &Submission{Answer: "1", IsFirstGuess: true},
+			round:         8,
+			expectedStats: FlashcardStats{ViewCount: 7, NextReview: 9, Lapses: 1, RelearningStep: 2},
+		},
+		{
+			id:            "Second relearning step passed",
+			submission:    &Submission{Answer: "1", IsFirstGuess: true},
+			round:         9,
+			expectedStats: FlashcardStats{ViewCount: 8, NextReview: 12, Lapses: 1, RelearningStep: 3},
+		},
+		{
+			id:            "Graduation",
+			submission:    &Submission{Answer: "1", IsFirstGuess: true},
+			round:         12,
+			expectedStats: FlashcardStats{ViewCount: 9, Repetitions: 1, NextReview: 13, Lapses: 1},
+		},
+	}
+
+	for _, update := range updates {
+		session.Round = update.round
+		match := f.Submit(update.submission, session, NewDoublingScheduler())
+		require.True(t, match.IsCorrect(), update.id)
+		require.Equal(t, update.expectedStats, f.Stats, update.id)
+	}
+}
+
+func TestFlashcard_Submit_relearningFSRS(t *testing.T) {
+	f := &Flashcard{
+		Metadata: flashcardMetadata(1),
+		Stats:    FlashcardStats{ViewCount: 3, Repetitions: 3, NextReview: 10, Interval: 10, Stability: 10, Difficulty: 5},
+	}
+
+	session := &Session{SessionOptions: SessionOptions{RelearningSteps: []int{1, 2}}}
+	scheduler := NewFSRSScheduler()
+
+	session.Round = 10
+	f.Submit(&Submission{Answer: "1", IsFirstGuess: false}, session, scheduler)
+	require.Equal(t, 1, f.Stats.RelearningStep)
+	require.Equal(t, 11, f.Stats.NextReview)
+	require.Equal(t, 1, f.Stats.Interval)
+
+	session.Round = 11
+	f.Submit(&Submission{Answer: "1", IsFirstGuess: true}, session, scheduler)
+	require.Equal(t, 2, f.Stats.RelearningStep)
+	require.Equal(t, 13, f.Stats.NextReview)
+	require.Equal(t, 2, f.Stats.Interval)
+
+	// Graduation should only count the rounds since the last relearning step.
+	session.Round = 13
+	expectedStability, expectedDifficulty := scheduler.model.nextState(f.Stats.Stability, f.Stats.Difficulty, 2, GradeGood)
+	f.Submit(&Submission{Answer: "1", IsFirstGuess: true}, session, scheduler)
+	require.Zero(t, f.Stats.RelearningStep)
+	require.Equal(t, expectedStability, f.Stats.Stability)
+	require.Equal(t, expectedDifficulty, f.Stats.Difficulty)
+	require.Equal(t, 13+f.Stats.Interval, f.Stats.NextReview)
+}
+
+func TestFlashcard_Submit_lapse(t *testing.T) {
+	f := &Flashcard{
+		Metadata: flashcardMetadata(1),
+		Stats:    FlashcardStats{ViewCount: 1, Repetitions: 1, NextReview: 1},
+	}
+
+	match := f.Submit(&Submission{Answer: "1"}, &Session{Round: 1}, NewDoublingScheduler())
+	require.True(t, match.IsCorrect())
+	require.Equal(t, FlashcardStats{ViewCount: 2, NextReview: 2, Lapses: 1}, f.Stats)
+}
+
+func TestFlashcard_Submit_typo(t *testing.T) {
+	f := &Flashcard{
+		Metadata: FlashcardMetadata{ID: 1, Prompt: "Big animal", Answer: "elephant"},
+		Stats:    FlashcardStats{ViewCount: 1, Repetitions: 1, NextReview: 1},
+	}
+
+	session := &Session{Round: 1, SessionOptions: SessionOptions{TypoTolerance: 0.2, LeechThreshold: 1}}
+
+	// A tolerated typo is graded as hard rather than as a lapse.
+	submission := &Submission{Answer: "elehpant", IsFirstGuess: true}
+	match := f.Submit(submission, session, NewDoublingScheduler())
+	require.True(t, match.TypoTolerated)
+	require.Equal(t, GradeHard, submission.counted(&FlashcardStats{}, match, session).grade())
+	require.Equal(t, FlashcardStats{ViewCount: 2, Repetitions: 1, NextReview: 2}, f.Stats)
+
+	// Other warnings still don't count as first guesses.
+	session.IgnoreAccents = true
+	f.Metadata.Answer = "éléphant"
+	match = f.Submit(&Submission{Answer: "elephant", IsFirstGuess: true}, session, NewDoublingScheduler())
+	require.False(t, match.TypoTolerated)
+	require.Equal(t, FlashcardStats{ViewCount: 3, NextReview: 2, Lapses: 1, IsLeech: true}, f.Stats)
+}
+
+func TestFlashcard_Submit_fuzz(t *testing.T) {
+	session := &Session{Round: 10, SessionOptions: SessionOptions{IntervalFuzz: 0.5, Seed: 1}}
+	stats := FlashcardStats{ViewCount: 5, Repetitions: 5, NextReview: 10}
+
+	nextReviews := make(map[int]bool)
+
+	for i := 1; i <= 10; i++ {
+		f := &Flashcard{Metadata: flashcardMetadata(i), Stats: stats}
+		match := f.Submit(&Submission{Answer: f.Metadata.Answer, IsFirstGuess: true}, session, NewDoublingScheduler())
+		require.True(t, match.IsCorrect())
+		require.InDelta(t, 42, f.Stats.NextReview, 16)
+
+		again := &Flashcard{Metadata: flashcardMetadata(i), Stats: stats}
+		again.Submit(&Submission{Answer: f.Metadata.Answer, IsFirstGuess: true}, session, NewDoublingScheduler())
+		require.Equal(t, f.Stats, again.Stats)
+
+		nextReviews[f.Stats.NextReview] = true
+	}
+
+	require.Greater(t, len(nextReviews), 1)
+}
+
+func TestFlashcard_Submit_revealed(t *testing.T) {
+	f := &Flashcard{
+		Metadata: flashcardMetadata(1),
+		Stats:    FlashcardStats{Revealed: true},
+	}
+
+	submission := &Submission{Answer: "1", IsFirstGuess: true}
+
+	match := f.Submit(submission, &Session{}, NewDoublingScheduler())
+	require.True(t, match.IsCorrect())
+	require.True(t, submission.IsFirstGuess)
+	require.Equal(t, FlashcardStats{ViewCount: 1, Repetitions: 0, NextReview: 1}, f.Stats)
+}
+
+func TestFlashcard_Submit_normalizers(t *testing.T) {
+	f := &Flashcard{
+		Metadata: FlashcardMetadata{ID: 1, Prompt: "Where?", Answer: "Zu\u0308rich"},
+	}
+
+	session := &Session{SessionOptions: SessionOptions{Normalizers: []string{NormalizerTrim, NormalizerCaseFold}}}
+
+	match := f.Submit(&Submission{Answer: " z\u00fcrich "}, session, NewDoublingScheduler())
+	require.False(t, match.IsCorrect())
+
+	session.Normalizers = append(session.Normalizers, NormalizerNFC)
+
+	match = f.Submit(&Submission{Answer: " z\u00fcrich "}, session, NewDoublingScheduler())
+	require.True(t, match.IsCorrect())
+	require.Equal(t, 1, f.Stats.ViewCount)
+}
+
+func TestSubmission_grade(t *testing.T) {
+	testCases := []struct {
+		submission    *Submission
+		expectedGrade Grade
+	}{
+		{submission: &Submission{IsFirstGuess: true}, expectedGrade: GradeGood},
+		{submission: &Submission{IsFirstGuess: false}, expectedGrade: GradeAgain},
+		{submission: &Submission{IsFirstGuess: true, Grade: GradeHard}, expectedGrade: GradeHard},
+		{submission: &Submission{IsFirstGuess: false, Grade: GradeEasy}, expectedGrade: GradeAgain},
+	}
+
+	for i, tc := range testCases {
+		require.Equal(t, tc.expectedGrade, tc.submission.grade(), i)
+	}
+}
+
+func Test_recordIncorrectAnswer(t *testing.T) {
+	var answers []string
+	for i := range maxIncorrectAnswers + 2 {
+		answers = recordIncorrectAnswer(answers, strconv.Itoa(i))
+	}
+
+	require.Len(t, answers, maxIncorrectAnswers)
+	require.Equal(t, "2", answers[0])
+	require.Equal(t, strconv.Itoa(maxIncorrectAnswers+1), answers[len(answers)-1])
+
+	answers = recordIncorrectAnswer(nil, strings.Repeat("é", maxIncorrectAnswerLength+1))
+	require.Equal(t, []string{strings.Repeat("é", maxIncorrectAnswerLength) + "…"}, answers)
+}
+
 func flashcardMetadata(i int) FlashcardMetadata {
 	return FlashcardMetadata{
 		ID:     int64(i),
@@ -0,0 +1,161 @@
+package review
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// FSRSSchedulerName identifies the FSRSScheduler.
+	FSRSSchedulerName = "fsrs"
+
+	// DefaultTargetRetention is the probability of recalling a flashcard that
+	// the FSRSScheduler aims for if the session doesn't specify otherwise.
+	DefaultTargetRetention = 0.9
+
+	fsrsDecay         = -0.5
+	fsrsFactor        = 19.0 / 81.0
+	fsrsMinStability  = 0.1
+	fsrsMinDifficulty = 1
+	fsrsMaxDifficulty = 10
+	fsrsMaxInterval   = 36500
+)
+
+// DefaultFSRSWeights are the FSRS-4.5 model weights that were fitted to a
+// large collection of Anki review histories.
+var DefaultFSRSWeights = []float64{
+	0.4872, 1.4003, 3.7145, 13.8206, 5.1618, 1.2298, 0.8975, 0.031, 1.6474,
+	0.1367, 1.0461, 2.1072, 0.0793, 0.3246, 1.587, 0.2272, 2.8755,
+}
+
+// FSRSScheduler implements the Free Spaced Repetition Scheduler, which models
+// each flashcard's memory state in terms of its stability (the number of
+// rounds or days until the probability of recall drops to 90%) and difficulty. The
+// next review is scheduled for when the probability of recall is predicted to
+// drop to the session's target retention.
+type FSRSScheduler struct {
+	model fsrsModel
+}
+
+// NewFSRSScheduler returns a new FSRSScheduler that uses the default weights.
+func NewFSRSScheduler() *FSRSScheduler {
+	return &FSRSScheduler{model: fsrsModel(DefaultFSRSWeights)}
+}
+
+// Name uniquely identifies the scheduler.
+func (s *FSRSScheduler) Name() string {
+	return FSRSSchedulerName
+}
+
+// Schedule returns the updated stats for a flashcard that has just been
+// answered correctly, along with the number of rounds until its next review.
+func (s *FSRSScheduler) Schedule(session *Session, stats FlashcardStats, submission *Submission) (FlashcardStats, int) {
+	model := s.model
+	if len(session.FSRSWeights) == len(model) {
+		model = session.FSRSWeights
+	}
+
+	rating := submission.grade()
+
+	if stats.Stability == 0 {
+		stats.Stability, stats.Difficulty = model.initialState(rating)
+	} else {
+		elapsed := fsrsElapsed(session, &stats, submission.Time)
+		stats.Stability, stats.Difficulty = model.nextState(stats.Stability, stats.Difficulty, float64(elapsed), rating)
+	}
+
+	if rating == GradeAgain {
+		stats.Repetitions = 0
+	} else {
+		stats.Repetitions++
+	}
+
+	stats.Interval = fsrsInterval(stats.Stability, session.targetRetention())
+	stats.LastReviewedAt = submission.Time
+
+	return stats, stats.Interval
+}
+
+// fsrsElapsed returns the number of rounds (or days, for time-based sessions)
+// since the flashcard was last reviewed.
+func fsrsElapsed(session *Session, stats *FlashcardStats, now time.Time) int {
+	if session.TimeBased {
+		return int(now.Sub(stats.LastReviewedAt) / day)
+	}
+	return session.Round - (stats.NextReview - stats.Interval)
+}
+
+// retrievability returns the probability of recalling a flashcard with the
+// specified stability after the specified amount of time has elapsed.
+func retrievability(elapsed float64, stability float64) float64 {
+	return math.Pow(1+fsrsFactor*elapsed/stability, fsrsDecay)
+}
+
+// fsrsInterval returns the number of rounds until the probability of recall
+// drops to the target retention.
+func fsrsInterval(stability float64, targetRetention float64) int {
+	interval := stability / fsrsFactor * (math.Pow(targetRetention, 1/fsrsDecay) - 1)
+	return min(max(int(math.Round(interval)), 1), fsrsMaxInterval)
+}
+
+// fsrsModel holds the weights of the FSRS memory model.
+type fsrsModel []float64
+
+// initialState returns the stability and difficulty after the first review.
+func (w fsrsModel) initialState(rating Grade) (float64, float64) {
+	return w.initialStability(rating), w.initialDifficulty(rating)
+}
+
+// nextState returns the stability and difficulty after a subsequent review,
+// given the number of rounds that have elapsed since the previous review.
+func (w fsrsModel) nextState(stability float64, difficulty float64, elapsed float64, rating Grade) (float64, float64) {
+	r := retrievability(elapsed, stability)
+	if rating == GradeAgain {
+		stability = w.forgetStability(difficulty, stability, r)
+	} else {
+		stability = w.recallStability(difficulty, stability, r, rating)
+	}
+	return stability, w.nextDifficulty(difficulty, rating)
+}
+
+func (w fsrsModel) initialStability(rating Grade) float64 {
+	return max(w[rating-1], fsrsMinStability)
+}
+
+func (w fsrsModel) initialDifficulty(rating Grade) float64 {
+	return clampDifficulty(w[4] - float64(rating-GradeGood)*w[5])
+}
+
+func (w fsrsModel) nextDifficulty(difficulty float64, rating Grade) float64 {
+	next := difficulty - w[6]*float64(rating-GradeGood)
+	return clampDifficulty(w[7]*w.initialDifficulty(GradeGood) + (1-w[7])*next)
+}
+
+func (w fsrsModel) recallStability(difficulty float64, stability float64, r float64, rating Grade) float64 {
+	hardPenalty, easyBonus := 1.0, 1.0
+	if rating == GradeHard {
+		hardPenalty = w[15]
+	}
+	if rating == GradeEasy {
+		easyBonus = w[16]
+	}
+	growth := math.Exp(w[8]) *
+		(fsrsMaxDifficulty + 1 - difficulty) *
+		math.Pow(stability, -w[9]) *
+		(math.Exp(w[10]*(1-r)) - 1) *
+		hardPenalty *
+		easyBonus
+	return stability * (growth + 1)
+}
+
+func (w fsrsModel) forgetStability(difficulty float64, stability float64, r float64) float64 {
+	next := w[11] *
+		math.Pow(difficulty, -w[12]) *
+		(math.Pow(stability+1, w[13]) - 1) *
+		math.Exp(w[14]*(1-r))
+	return min(next, stability)
+}
+
+func clampDifficulty(difficulty float64) float64 {
+	return min(max(difficulty, fsrsMinDifficulty), fsrsMaxDifficulty)
+}
@@ -0,0 +1,104 @@
+package review
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFSRSScheduler_Schedule(t *testing.T) {
+	updates := []struct {
+		id                 string
+		isFirstGuess       bool
+		expectedStability  float64
+		expectedDifficulty float64
+		expectedNextReview int
+		expectedInterval   int
+	}{
+		{
+			id:                 "Correct first review",
+			isFirstGuess:       true,
+			expectedStability:  3.7145,
+			expectedDifficulty: 5.1618,
+			expectedNextReview: 4,
+			expectedInterval:   4,
+		},
+		{
+			id:                 "Correct second review",
+			isFirstGuess:       true,
+			expectedStability:  14.8081,
+			expectedDifficulty: 5.1618,
+			expectedNextReview: 19,
+			expectedInterval:   15,
+		},
+		{
+			id:                 "Corrected third review",
+			isFirstGuess:       false,
+			expectedStability:  3.1493,
+			expectedDifficulty: 6.9012,
+			expectedNextReview: 22,
+			expectedInterval:   3,
+		},
+	}
+
+	f := &Flashcard{Metadata: flashcardMetadata(1)}
+	session := &Session{}
+	scheduler := NewFSRSScheduler()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, update := range updates {
+		submission := &Submission{Answer: f.Metadata.Answer, IsFirstGuess: update.isFirstGuess, Time: now}
+		match := f.Submit(submission, session, scheduler)
+		require.True(t, match.IsCorrect(), update.id)
+		require.InDelta(t, update.expectedStability, f.Stats.Stability, 1e-4, update.id)
+		require.InDelta(t, update.expectedDifficulty, f.Stats.Difficulty, 1e-4, update.id)
+		require.Equal(t, update.expectedNextReview, f.Stats.NextReview, update.id)
+		require.Equal(t, update.expectedInterval, f.Stats.Interval, update.id)
+		require.Equal(t, now, f.Stats.LastReviewedAt, update.id)
+		session.Round = f.Stats.NextReview
+	}
+}
+
+func TestFSRSScheduler_sessionWeights(t *testing.T) {
+	weights := slices.Clone(DefaultFSRSWeights)
+	weights[GradeGood-1] = 20
+
+	session := &Session{SessionOptions: SessionOptions{FSRSWeights: weights}}
+
+	stats, next := NewFSRSScheduler().Schedule(session, FlashcardStats{}, &Submission{IsFirstGuess: true})
+	require.InDelta(t, 20, stats.Stability, 0)
+	require.Equal(t, 20, next)
+}
+
+func TestFSRSScheduler_targetRetention(t *testing.T) {
+	testCases := []struct {
+		targetRetention  float64
+		expectedInterval int
+	}{
+		{targetRetention: 0, expectedInterval: 10},
+		{targetRetention: 0.9, expectedInterval: 10},
+		{targetRetention: 0.8, expectedInterval: 24},
+		{targetRetention: 0.95, expectedInterval: 5},
+	}
+
+	for _, tc := range testCases {
+		session := &Session{SessionOptions: SessionOptions{TargetRetention: tc.targetRetention}}
+		require.Equal(t, tc.expectedInterval, fsrsInterval(10, session.targetRetention()), tc.targetRetention)
+	}
+}
+
+func Test_retrievability(t *testing.T) {
+	require.InDelta(t, 1.0, retrievability(0, 5), 1e-9)
+	require.InDelta(t, 0.9, retrievability(5, 5), 1e-9)
+	require.Less(t, retrievability(10, 5), 0.9)
+}
+
+func Test_fsrsElapsed(t *testing.T) {
+	now := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	stats := &FlashcardStats{NextReview: 8, Interval: 5, LastReviewedAt: now.Add(-3*day - time.Hour)}
+
+	require.Equal(t, 4, fsrsElapsed(&Session{Round: 7}, stats, now))
+	require.Equal(t, 3, fsrsElapsed(&Session{SessionOptions: SessionOptions{TimeBased: true}}, stats, now))
+}
@@ -0,0 +1,53 @@
+package review
+
+const (
+	// LeitnerSchedulerName identifies the LeitnerScheduler.
+	LeitnerSchedulerName = "leitner"
+)
+
+// DefaultBoxCadences are the box cadences used by the LeitnerScheduler if the
+// session doesn't specify otherwise.
+var DefaultBoxCadences = []int{1, 2, 5, 10, 20}
+
+// LeitnerScheduler implements the Leitner system, where each flashcard lives in
+// a numbered box. A correct first guess moves the flashcard up to the next box
+// (or two boxes if it was easy, or not at all if it was hard), while anything
+// else moves it back down to the first box. The flashcards in
+// each box are reviewed together on a fixed cadence, e.g. the first box every
+// round, the second box every second round, the third box every fifth round, etc.
+//
+// The box is stored as the flashcard's number of repetitions, with 0 being the
+// first box, so the session's proficiency counts correspond to the box sizes.
+type LeitnerScheduler struct{}
+
+// NewLeitnerScheduler returns a new LeitnerScheduler.
+func NewLeitnerScheduler() *LeitnerScheduler {
+	return &LeitnerScheduler{}
+}
+
+// Name uniquely identifies the scheduler.
+func (s *LeitnerScheduler) Name() string {
+	return LeitnerSchedulerName
+}
+
+// Schedule returns the updated stats for a flashcard that has just been
+// answered correctly, along with the number of rounds until its next review.
+func (s *LeitnerScheduler) Schedule(session *Session, stats FlashcardStats, submission *Submission) (FlashcardStats, int) {
+	cadences := session.boxCadences()
+
+	switch submission.grade() {
+	case GradeAgain:
+		stats.Repetitions = 0
+	case GradeHard:
+		stats.Repetitions = min(stats.Repetitions, len(cadences)-1)
+	case GradeGood:
+		stats.Repetitions = min(stats.Repetitions+1, len(cadences)-1)
+	case GradeEasy:
+		stats.Repetitions = min(stats.Repetitions+easyIncrement, len(cadences)-1)
+	}
+
+	// Review the flashcard in the next round (or day) in which its box is due.
+	cadence := cadences[stats.Repetitions]
+
+	return stats, cadence - session.period(submission.Time)%cadence
+}
@@ -0,0 +1,55 @@
+package review
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeitnerScheduler_Schedule(t *testing.T) {
+	updates := []struct {
+		id                 string
+		isFirstGuess       bool
+		round              int
+		expectedBox        int
+		expectedNextReview int
+	}{
+		{id: "Correct first review", isFirstGuess: true, round: 0, expectedBox: 1, expectedNextReview: 2},
+		{id: "Correct second review", isFirstGuess: true, round: 2, expectedBox: 2, expectedNextReview: 5},
+		{id: "Correct third review", isFirstGuess: true, round: 5, expectedBox: 3, expectedNextReview: 10},
+		{id: "Correct fourth review", isFirstGuess: true, round: 10, expectedBox: 3, expectedNextReview: 20},
+		{id: "Corrected fifth review", isFirstGuess: false, round: 20, expectedBox: 0, expectedNextReview: 21},
+		{id: "Correct sixth review", isFirstGuess: true, round: 21, expectedBox: 1, expectedNextReview: 22},
+	}
+
+	f := &Flashcard{Metadata: flashcardMetadata(1)}
+	session := &Session{SessionOptions: SessionOptions{BoxCadences: []int{1, 2, 5, 10}}}
+	scheduler := NewLeitnerScheduler()
+
+	for _, update := range updates {
+		session.Round = update.round
+		submission := &Submission{Answer: f.Metadata.Answer, IsFirstGuess: update.isFirstGuess}
+		match := f.Submit(submission, session, scheduler)
+		require.True(t, match.IsCorrect(), update.id)
+		require.Equal(t, update.expectedBox, f.Stats.Repetitions, update.id)
+		require.Equal(t, update.expectedNextReview, f.Stats.NextReview, update.id)
+	}
+}
+
+func TestLeitnerScheduler_grades(t *testing.T) {
+	testCases := []struct {
+		grade       Grade
+		expectedBox int
+	}{
+		{grade: GradeAgain, expectedBox: 0},
+		{grade: GradeHard, expectedBox: 2},
+		{grade: GradeGood, expectedBox: 3},
+		{grade: GradeEasy, expectedBox: 4},
+	}
+
+	for _, tc := range testCases {
+		stats := FlashcardStats{Repetitions: 2}
+		stats, _ = NewLeitnerScheduler().Schedule(&Session{}, stats, &Submission{IsFirstGuess: true, Grade: tc.grade})
+		require.Equal(t, tc.expectedBox, stats.Repetitions, tc.grade)
+	}
+}
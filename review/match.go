@@ -0,0 +1,315 @@
+package review
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Outcomes of a submission.
+const (
+	// OutcomeCorrect means that the answer was accepted as is.
+	OutcomeCorrect = "correct"
+	// OutcomeWarning means that the answer was accepted, but differs slightly
+	// from the expected answer, e.g. in its accents or because of a typo.
+	OutcomeWarning = "warning"
+	// OutcomeWrong means that the answer wasn't accepted.
+	OutcomeWrong = "wrong"
+	// OutcomePartial means that the answer was accepted as one of the answers to
+	// a merged flashcard, but that other answers are still missing.
+	OutcomePartial = "partial"
+	// OutcomeRepeated means that the answer is one of the answers to a merged
+	// flashcard that has already been named, so it counts neither way.
+	OutcomeRepeated = "repeated"
+)
+
+// Match describes how a submitted answer compares to the expected answer.
+type Match struct {
+	// Outcome is either OutcomeCorrect, OutcomeWarning, OutcomeWrong, OutcomePartial or OutcomeRepeated.
+	Outcome string `json:"outcome"`
+	// Differences are the characters whose accents differ from the expected answer (OutcomeWarning only).
+	Differences []Difference `json:"differences,omitempty"`
+	// TypoTolerated is true if and only if the answer was accepted despite containing typos.
+	TypoTolerated bool `json:"typoTolerated,omitempty"`
+	// Answer is the canonical answer, included if the submitted answer differs from it.
+	// In multiple-choice sessions, it's only included if a wrong choice was picked, since
+	// the answer is hidden from the flashcard metadata there.
+	Answer string `json:"answer,omitempty"`
+	// Remaining is the number of answers that are still missing (OutcomePartial and OutcomeRepeated only).
+	Remaining int `json:"remaining,omitempty"`
+
+	// part is the index of the matched part (merged flashcards only).
+	part int
+}
+
+// Difference is a part of a submitted answer that differs from the expected answer.
+type Difference struct {
+	// Expected is the expected character.
+	Expected string `json:"expected"`
+	// Submitted is the submitted character.
+	Submitted string `json:"submitted"`
+}
+
+// IsCorrect returns true if and only if the flashcard was answered correctly.
+func (m *Match) IsCorrect() bool {
+	return m.Outcome == OutcomeCorrect || m.Outcome == OutcomeWarning
+}
+
+// match compares the answer to the flashcard's accepted answers or, for merged
+// flashcards, to the answers that haven't been named yet. Naming an answer to a
+// merged flashcard again is reported as such, rather than as a wrong answer.
+// Numeric answers are compared numerically instead. In multiple-choice sessions,
+// only the exact answer is accepted, since it's one of the choices.
+func (f *Flashcard) match(answer string, session *Session) *Match {
+	if f.isMultipleChoice(session) {
+		if answer == f.Metadata.Answer {
+			return &Match{Outcome: OutcomeCorrect}
+		}
+		return &Match{Outcome: OutcomeWrong, Answer: f.Metadata.Answer}
+	}
+
+	if f.Metadata.AnswerType == AnswerTypeNumeric {
+		return f.matchNumber(answer, session)
+	}
+
+	if len(f.Metadata.Parts) == 0 {
+		return matchAnswers(answer, f.Metadata.acceptedAnswers(), f.Metadata.Pattern, session)
+	}
+
+	var best *Match
+	var isRepeated bool
+
+	for i, part := range f.Metadata.Parts {
+		m := matchAnswers(answer, part.acceptedAnswers(), part.Pattern, session)
+		if slices.Contains(f.Stats.NamedParts, i) {
+			isRepeated = isRepeated || m.IsCorrect()
+			continue
+		}
+		if m.IsCorrect() && (best == nil || (best.Outcome == OutcomeWarning && m.Outcome == OutcomeCorrect)) {
+			best = m
+			best.part = i
+		}
+	}
+
+	if best == nil && isRepeated {
+		return &Match{Outcome: OutcomeRepeated, Remaining: len(f.Metadata.Parts) - len(f.Stats.NamedParts)}
+	}
+
+	if best == nil {
+		return &Match{Outcome: OutcomeWrong}
+	}
+
+	if remaining := len(f.Metadata.Parts) - len(f.Stats.NamedParts) - 1; remaining > 0 {
+		best.Outcome = OutcomePartial
+		best.Remaining = remaining
+	}
+
+	return best
+}
+
+// matchAnswers compares the answer to the accepted answers (the first of which
+// is the canonical one) after applying the session's normalizers. An answer that
+// matches the pattern (if any) in full, before or after normalization, is also
+// accepted. If the session ignores accents, an answer that only differs in its
+// accents is accepted with a warning (and one that only differs in how its
+// accents are encoded is accepted without one). Similarly, if the session
+// tolerates typos, an answer that is close enough to an accepted answer is
+// accepted with a warning.
+func matchAnswers(answer string, acceptedAnswers []string, pattern string, session *Session) *Match {
+	submitted := session.normalize(answer)
+
+	accepted := make([]string, len(acceptedAnswers))
+	for i := range acceptedAnswers {
+		accepted[i] = session.normalize(acceptedAnswers[i])
+	}
+
+	// accept includes the canonical answer as feedback unless the answer was
+	// correct and already the canonical one.
+	accept := func(match *Match, i int) *Match {
+		if i > 0 || match.Outcome == OutcomeWarning {
+			match.Answer = acceptedAnswers[0]
+		}
+		return match
+	}
+
+	if i := slices.Index(accepted, submitted); i >= 0 {
+		return accept(&Match{Outcome: OutcomeCorrect}, i)
+	}
+
+	if pattern != "" {
+		// Patterns are validated when the flashcards are loaded and again
+		// before each submission.
+		re, err := compilePattern(pattern)
+		if err == nil && (re.MatchString(answer) || re.MatchString(submitted)) {
+			return accept(&Match{Outcome: OutcomeCorrect}, len(accepted))
+		}
+	}
+
+	if session.IgnoreAccents {
+		for i, expected := range accepted {
+			differences, ok := accentDifferences(expected, submitted)
+			switch {
+			case ok && len(differences) == 0:
+				return accept(&Match{Outcome: OutcomeCorrect}, i)
+			case ok:
+				return accept(&Match{Outcome: OutcomeWarning, Differences: differences}, i)
+			}
+		}
+	}
+
+	if session.TypoTolerance > 0 {
+		submittedRunes := []rune(submitted)
+		for i, expected := range accepted {
+			expectedRunes := []rune(expected)
+			maxTypos := int(session.TypoTolerance * float64(len(expectedRunes)))
+			// The difference in length is a lower bound on the edit distance,
+			// so there's no need to compute it if that's already too large.
+			if abs(len(expectedRunes)-len(submittedRunes)) > maxTypos {
+				continue
+			}
+			if editDistance(expectedRunes, submittedRunes) <= maxTypos {
+				return accept(&Match{Outcome: OutcomeWarning, TypoTolerated: true}, i)
+			}
+		}
+	}
+
+	return &Match{Outcome: OutcomeWrong}
+}
+
+// compiledPatterns caches the compiled patterns by pattern, since the same
+// patterns are matched against every submission.
+var compiledPatterns sync.Map
+
+// compilePattern compiles a pattern that must match the whole answer. Each
+// pattern is only compiled once.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := compiledPatterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+
+	// The pattern is parsed on its own first, so that it can't escape the
+	// surrounding group, e.g. "a)|(b".
+	_, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, fmt.Errorf("pattern %s: %w: %w", pattern, ErrInvalidPattern, err)
+	}
+
+	re, err := regexp.Compile(`^(?:` + pattern + `)$`)
+	if err != nil {
+		return nil, fmt.Errorf("pattern %s: %w: %w", pattern, ErrInvalidPattern, err)
+	}
+
+	compiledPatterns.Store(pattern, re)
+
+	return re, nil
+}
+
+// accentDifferences returns the characters whose accents differ between the
+// expected and the submitted answers, or false if the answers also differ in
+// other ways.
+func accentDifferences(expected, submitted string) ([]Difference, bool) {
+	expectedChars := splitCharacters(expected)
+	submittedChars := splitCharacters(submitted)
+
+	if len(expectedChars) != len(submittedChars) {
+		return nil, false
+	}
+
+	var differences []Difference
+
+	for i := range expectedChars {
+		if expectedChars[i] == submittedChars[i] {
+			continue
+		}
+		if stripMarks(expectedChars[i]) != stripMarks(submittedChars[i]) {
+			return nil, false
+		}
+		differences = append(differences, Difference{
+			Expected:  norm.NFC.String(expectedChars[i]),
+			Submitted: norm.NFC.String(submittedChars[i]),
+		})
+	}
+
+	return differences, true
+}
+
+// splitCharacters decomposes the string and splits it into characters, each
+// consisting of a base rune followed by its combining marks. The first element
+// holds any leading marks, so that it's empty for well-formed strings.
+func splitCharacters(s string) []string {
+	chars := []string{""}
+	for _, r := range norm.NFD.String(s) {
+		if unicode.Is(unicode.Mn, r) {
+			chars[len(chars)-1] += string(r)
+		} else {
+			chars = append(chars, string(r))
+		}
+	}
+	return chars
+}
+
+// stripMarks removes the combining marks from a decomposed string.
+func stripMarks(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Mn, r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// editDistance returns the Damerau–Levenshtein distance between the strings
+// (in its optimal string alignment variant), i.e. the minimum number of
+// insertions, deletions, substitutions and transpositions of adjacent runes
+// needed to turn one into the other, without editing any substring twice.
+func editDistance(a, b []rune) int {
+	// Only the last three rows of the distance matrix are needed, where prev[j]
+	// is the distance between the first i-1 runes of a and the first j runes of
+	// b, and likewise for prevPrev (i-2) and curr (i).
+	prevPrev := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			curr[j] = min(
+				prev[j]+1,      // deletion
+				curr[j-1]+1,    // insertion
+				prev[j-1]+cost, // substitution
+			)
+
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				curr[j] = min(curr[j], prevPrev[j-2]+1) // transposition
+			}
+		}
+
+		prevPrev, prev, curr = prev, curr, prevPrev
+	}
+
+	return prev[len(b)]
+}
+
+// abs returns the absolute value of the integer.
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
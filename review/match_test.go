@@ -0,0 +1,273 @@
+package review
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlashcard_match(t *testing.T) {
+	testCases := []struct {
+		id           string
+		expected     string
+		alternatives []string
+		pattern      string
+		answer       string
+		options      SessionOptions
+		match        *Match
+	}{
+		{
+			id:       "Exact",
+			expected: "café",
+			answer:   "café",
+			match:    &Match{Outcome: OutcomeCorrect},
+		},
+		{
+			id:       "Accents not ignored",
+			expected: "café",
+			answer:   "cafe",
+			match:    &Match{Outcome: OutcomeWrong},
+		},
+		{
+			id:       "Missing accent",
+			expected: "café",
+			answer:   "cafe",
+			options:  SessionOptions{IgnoreAccents: true},
+			match: &Match{
+				Outcome:     OutcomeWarning,
+				Differences: []Difference{{Expected: "é", Submitted: "e"}},
+				Answer:      "café",
+			},
+		},
+		{
+			id:       "Wrong accents",
+			expected: "Ärger über",
+			answer:   "Àrger uber",
+			options:  SessionOptions{IgnoreAccents: true},
+			match: &Match{
+				Outcome: OutcomeWarning,
+				Differences: []Difference{
+					{Expected: "Ä", Submitted: "À"},
+					{Expected: "ü", Submitted: "u"},
+				},
+				Answer: "Ärger über",
+			},
+		},
+		{
+			id:       "Decomposed accent",
+			expected: "caf\u00e9",
+			answer:   "cafe\u0301",
+			options:  SessionOptions{IgnoreAccents: true},
+			match:    &Match{Outcome: OutcomeCorrect},
+		},
+		{
+			id:       "Decomposed wrong accent",
+			expected: "caf\u00e9",
+			answer:   "cafe\u0300",
+			options:  SessionOptions{IgnoreAccents: true},
+			match: &Match{
+				Outcome:     OutcomeWarning,
+				Differences: []Difference{{Expected: "\u00e9", Submitted: "\u00e8"}},
+				Answer:      "caf\u00e9",
+			},
+		},
+		{
+			id:       "Different letters",
+			expected: "café",
+			answer:   "cafa",
+			options:  SessionOptions{IgnoreAccents: true},
+			match:    &Match{Outcome: OutcomeWrong},
+		},
+		{
+			id:       "Different length",
+			expected: "café",
+			answer:   "cafés",
+			options:  SessionOptions{IgnoreAccents: true},
+			match:    &Match{Outcome: OutcomeWrong},
+		},
+		{
+			id:       "Typo not tolerated",
+			expected: "necessary",
+			answer:   "neccessary",
+			match:    &Match{Outcome: OutcomeWrong},
+		},
+		{
+			id:       "Tolerated typo",
+			expected: "necessary",
+			answer:   "neccessary",
+			options:  SessionOptions{TypoTolerance: 0.2},
+			match:    &Match{Outcome: OutcomeWarning, TypoTolerated: true, Answer: "necessary"},
+		},
+		{
+			id:       "Tolerated transposition",
+			expected: "necessary",
+			answer:   "necessray",
+			options:  SessionOptions{TypoTolerance: 0.2},
+			match:    &Match{Outcome: OutcomeWarning, TypoTolerated: true, Answer: "necessary"},
+		},
+		{
+			id:       "Too many typos",
+			expected: "necessary",
+			answer:   "neccessray",
+			options:  SessionOptions{TypoTolerance: 0.2},
+			match:    &Match{Outcome: OutcomeWrong},
+		},
+		{
+			id:       "Answer too short for typos",
+			expected: "cat",
+			answer:   "car",
+			options:  SessionOptions{TypoTolerance: 0.2},
+			match:    &Match{Outcome: OutcomeWrong},
+		},
+		{
+			id:       "Accents preferred over typos",
+			expected: "café",
+			answer:   "cafe",
+			options:  SessionOptions{IgnoreAccents: true, TypoTolerance: 0.5},
+			match: &Match{
+				Outcome:     OutcomeWarning,
+				Differences: []Difference{{Expected: "é", Submitted: "e"}},
+				Answer:      "café",
+			},
+		},
+		{
+			id:           "Alternative answer",
+			expected:     "colour",
+			alternatives: []string{"color"},
+			answer:       "color",
+			match:        &Match{Outcome: OutcomeCorrect, Answer: "colour"},
+		},
+		{
+			id:           "Alternative answer with typo",
+			expected:     "colour",
+			alternatives: []string{"color", "tint"},
+			answer:       "tnit",
+			options:      SessionOptions{TypoTolerance: 0.5},
+			match:        &Match{Outcome: OutcomeWarning, TypoTolerated: true, Answer: "colour"},
+		},
+		{
+			id:           "Exact alternative preferred over typo",
+			expected:     "form",
+			alternatives: []string{"from"},
+			answer:       "from",
+			options:      SessionOptions{TypoTolerance: 0.5},
+			match:        &Match{Outcome: OutcomeCorrect, Answer: "form"},
+		},
+		{
+			id:       "Pattern",
+			expected: "the capital",
+			pattern:  "(the )?capital",
+			answer:   "capital",
+			match:    &Match{Outcome: OutcomeCorrect, Answer: "the capital"},
+		},
+		{
+			id:       "Pattern must match in full",
+			expected: "the capital",
+			pattern:  "(the )?capital",
+			answer:   "the capital city",
+			match:    &Match{Outcome: OutcomeWrong},
+		},
+		{
+			id:       "Pattern after normalization",
+			expected: "the capital",
+			pattern:  "(the )?capital",
+			answer:   " Capital ",
+			options:  SessionOptions{Normalizers: []string{NormalizerTrim, NormalizerCaseFold}},
+			match:    &Match{Outcome: OutcomeCorrect, Answer: "the capital"},
+		},
+		{
+			id:       "Pattern with alternation",
+			expected: "grey",
+			pattern:  "gr[ae]y|ash",
+			answer:   "ash",
+			match:    &Match{Outcome: OutcomeCorrect, Answer: "grey"},
+		},
+	}
+
+	for _, tc := range testCases {
+		f := &Flashcard{Metadata: FlashcardMetadata{
+			ID:                 1,
+			Prompt:             "P",
+			Answer:             tc.expected,
+			AlternativeAnswers: tc.alternatives,
+			Pattern:            tc.pattern,
+		}}
+		session := &Session{SessionOptions: tc.options}
+		require.Equal(t, tc.match, f.match(tc.answer, session), tc.id)
+	}
+}
+
+func TestFlashcard_match_parts(t *testing.T) {
+	f := &Flashcard{
+		Metadata: FlashcardMetadata{
+			ID:     1,
+			Prompt: "Synonyms of big",
+			Answer: "large, huge, great",
+			Parts: []AnswerPart{
+				{Answer: "large"},
+				{Answer: "huge", AlternativeAnswers: []string{"enormous"}},
+				{Answer: "great"},
+			},
+		},
+		Stats: FlashcardStats{NamedParts: []int{2}},
+	}
+
+	session := &Session{SessionOptions: SessionOptions{TypoTolerance: 0.2}}
+
+	testCases := []struct {
+		answer string
+		match  *Match
+	}{
+		{answer: "large", match: &Match{Outcome: OutcomePartial, Remaining: 1}},
+		{answer: "enormous", match: &Match{Outcome: OutcomePartial, Answer: "huge", Remaining: 1, part: 1}},
+		{answer: "larg", match: &Match{Outcome: OutcomePartial, TypoTolerated: true, Answer: "large", Remaining: 1}},
+		{answer: "great", match: &Match{Outcome: OutcomeRepeated, Remaining: 2}},
+		{answer: "small", match: &Match{Outcome: OutcomeWrong}},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.match, f.match(tc.answer, session), tc.answer)
+	}
+
+	f.Stats.NamedParts = []int{2, 0}
+
+	require.Equal(t, &Match{Outcome: OutcomeCorrect, part: 1}, f.match("huge", session))
+}
+
+func TestCompilePattern(t *testing.T) {
+	re, err := compilePattern("(the )?capital")
+	require.NoError(t, err)
+
+	cached, err := compilePattern("(the )?capital")
+	require.NoError(t, err)
+	require.Same(t, re, cached)
+
+	_, err = compilePattern("(the capital")
+	require.ErrorIs(t, err, ErrInvalidPattern)
+
+	_, err = compilePattern("a)|(b")
+	require.ErrorIs(t, err, ErrInvalidPattern)
+}
+
+func TestEditDistance(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		expected int
+	}{
+		{a: "", b: "", expected: 0},
+		{a: "abc", b: "", expected: 3},
+		{a: "", b: "abc", expected: 3},
+		{a: "abc", b: "abc", expected: 0},
+		{a: "abc", b: "abd", expected: 1},
+		{a: "abc", b: "ab", expected: 1},
+		{a: "abc", b: "abcd", expected: 1},
+		{a: "abc", b: "acb", expected: 1},
+		{a: "ca", b: "abc", expected: 3},
+		{a: "kitten", b: "sitting", expected: 3},
+		{a: "über", b: "uber", expected: 1},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.expected, editDistance([]rune(tc.a), []rune(tc.b)), tc.a+" "+tc.b)
+	}
+}
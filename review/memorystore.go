@@ -12,6 +12,7 @@ import (
 type MemoryStore struct {
 	session    map[string]*Session
 	flashcards map[string][]*Flashcard
+	reviewLog  map[string][]*ReviewLogEntry
 }
 
 // NewMemoryStore returns a new empty MemoryStore.
@@ -19,6 +20,7 @@ func NewMemoryStore() *MemoryStore {
 	return &MemoryStore{
 		session:    make(map[string]*Session),
 		flashcards: make(map[string][]*Flashcard),
+		reviewLog:  make(map[string][]*ReviewLogEntry),
 	}
 }
 
@@ -122,35 +124,104 @@ func (s *MemoryStore) SetFlashcardStats(_ context.Context, sessionID string, fla
 	return nil
 }
 
+// SetSuspended suspends or unsuspends a flashcard.
+func (s *MemoryStore) SetSuspended(_ context.Context, sessionID string, flashcardID int64, suspended bool) error {
+	f, err := s.lookupFlashcard(sessionID, flashcardID)
+	if err != nil {
+		return err
+	}
+
+	f.Stats.Suspended = suspended
+
+	return nil
+}
+
+// SetBuriedUntil excludes a flashcard from reviews until the specified round (or day, for time-based sessions).
+func (s *MemoryStore) SetBuriedUntil(_ context.Context, sessionID string, flashcardID int64, period int) error {
+	f, err := s.lookupFlashcard(sessionID, flashcardID)
+	if err != nil {
+		return err
+	}
+
+	f.Stats.BuriedUntil = period
+
+	return nil
+}
+
+// GetChoiceCandidates returns up to the specified number of flashcards with
+// the specified context and up to the specified number of flashcards with
+// any context, whose answers can be offered as choices. The two may overlap.
+func (s *MemoryStore) GetChoiceCandidates(_ context.Context, sessionID, promptContext string, limit int) ([]*Flashcard, error) {
+	flashcards, ok := s.flashcards[sessionID]
+	if !ok {
+		return nil, fmt.Errorf("flashcards for session %s: %w", sessionID, ErrNotFound)
+	}
+
+	var sameContext []*Flashcard
+	if promptContext != "" {
+		for _, f := range flashcards {
+			if f.Metadata.Context == promptContext && len(sameContext) < limit {
+				sameContext = append(sameContext, f)
+			}
+		}
+	}
+
+	return append(sameContext, flashcards[:min(limit, len(flashcards))]...), nil
+}
+
 // NextReviewed returns a flashcard that is due to be reviewed again.
-func (s *MemoryStore) NextReviewed(_ context.Context, sessionID string, round int) (*Flashcard, error) {
+func (s *MemoryStore) NextReviewed(_ context.Context, sessionID string, query *ReviewQuery) (*Flashcard, error) {
 	flashcards, ok := s.flashcards[sessionID]
 	if !ok {
 		return nil, fmt.Errorf("flashcards for session %s: %w", sessionID, ErrNotFound)
 	}
 
+	// We intentionally don't preallocate the slice, because typically only a
+	// few flashcards are candidates.
+	var candidates []*Flashcard //nolint:prealloc
 	for _, f := range flashcards {
-		if f.Stats.ViewCount > 0 && f.Stats.NextReview <= round {
-			return f, nil
+		if f.Stats.ViewCount > 0 && f.Stats.isAvailable(query.period()) && query.isDue(&f.Stats) {
+			candidates = append(candidates, f)
 		}
 	}
-	return nil, ErrNotFound
+
+	return query.pick(candidates)
 }
 
 // NextUnreviewed returns a flashcard that has never been reviewed before.
-func (s *MemoryStore) NextUnreviewed(_ context.Context, sessionID string) (*Flashcard, error) {
+func (s *MemoryStore) NextUnreviewed(_ context.Context, sessionID string, query *ReviewQuery) (*Flashcard, error) {
 	flashcards, ok := s.flashcards[sessionID]
 	if !ok {
 		return nil, fmt.Errorf("flashcards for session %s: %w", sessionID, ErrNotFound)
 	}
 
+	// We intentionally don't preallocate the slice, because typically only a
+	// few flashcards are candidates.
+	var candidates []*Flashcard //nolint:prealloc
 	for _, f := range flashcards {
-		if f.Stats.ViewCount == 0 {
-			return f, nil
+		if f.Stats.ViewCount == 0 && f.Stats.isAvailable(query.period()) {
+			candidates = append(candidates, f)
 		}
 	}
 
-	return nil, ErrNotFound
+	return query.pick(candidates)
+}
+
+// CountSuspended returns the number of suspended flashcards.
+func (s *MemoryStore) CountSuspended(_ context.Context, sessionID string) (int, error) {
+	flashcards, ok := s.flashcards[sessionID]
+	if !ok {
+		return 0, fmt.Errorf("flashcards for session %s: %w", sessionID, ErrNotFound)
+	}
+
+	var count int
+	for _, f := range flashcards {
+		if f.Stats.Suspended {
+			count++
+		}
+	}
+
+	return count, nil
 }
 
 // GetSession returns the current session metadata.
@@ -176,3 +247,78 @@ func (s *MemoryStore) SetSession(_ context.Context, sessionID string, session *S
 	s.session[sessionID] = session
 	return nil
 }
+
+// AddReviewLogEntry appends an entry to the review log, assigning it the next sequence number.
+func (s *MemoryStore) AddReviewLogEntry(_ context.Context, sessionID string, entry *ReviewLogEntry) error {
+	if _, ok := s.session[sessionID]; !ok {
+		return fmt.Errorf("review log for session %s: %w", sessionID, ErrNotFound)
+	}
+
+	entries := s.reviewLog[sessionID]
+
+	entry.Seq = 1
+	if len(entries) > 0 {
+		entry.Seq = entries[len(entries)-1].Seq + 1
+	}
+
+	s.reviewLog[sessionID] = append(entries, entry)
+
+	return nil
+}
+
+// GetReviewLog returns all review log entries, from oldest to newest.
+func (s *MemoryStore) GetReviewLog(_ context.Context, sessionID string) ([]*ReviewLogEntry, error) {
+	if _, ok := s.session[sessionID]; !ok {
+		return nil, fmt.Errorf("review log for session %s: %w", sessionID, ErrNotFound)
+	}
+
+	return slices.Clone(s.reviewLog[sessionID]), nil
+}
+
+// GetReviewLogSinceLastCorrect returns the newest review log entries, from newest to oldest,
+// up to and including the newest correct one.
+func (s *MemoryStore) GetReviewLogSinceLastCorrect(_ context.Context, sessionID string) ([]*ReviewLogEntry, error) {
+	if _, ok := s.session[sessionID]; !ok {
+		return nil, fmt.Errorf("review log for session %s: %w", sessionID, ErrNotFound)
+	}
+
+	entries := s.reviewLog[sessionID]
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].IsCorrect {
+			tail := slices.Clone(entries[i:])
+			slices.Reverse(tail)
+			return tail, nil
+		}
+	}
+
+	return nil, fmt.Errorf("correct review log entries for session %s: %w", sessionID, ErrNotFound)
+}
+
+// DeleteReviewLogEntries deletes the review log entries with the specified sequence numbers.
+func (s *MemoryStore) DeleteReviewLogEntries(_ context.Context, sessionID string, seqs []int64) error {
+	if _, ok := s.session[sessionID]; !ok {
+		return fmt.Errorf("review log for session %s: %w", sessionID, ErrNotFound)
+	}
+
+	s.reviewLog[sessionID] = slices.DeleteFunc(slices.Clone(s.reviewLog[sessionID]), func(e *ReviewLogEntry) bool {
+		return slices.Contains(seqs, e.Seq)
+	})
+
+	return nil
+}
+
+func (s *MemoryStore) lookupFlashcard(sessionID string, flashcardID int64) (*Flashcard, error) {
+	flashcards, ok := s.flashcards[sessionID]
+	if !ok {
+		return nil, fmt.Errorf("flashcards for session %s: %w", sessionID, ErrNotFound)
+	}
+
+	for _, f := range flashcards {
+		if f.Metadata.ID == flashcardID {
+			return f, nil
+		}
+	}
+
+	return nil, fmt.Errorf("flashcard %d for session %s: %w", flashcardID, sessionID, ErrNotFound)
+}
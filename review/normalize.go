@@ -0,0 +1,65 @@
+package review
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalizers, which can be chained to make answer comparisons more lenient.
+const (
+	// NormalizerTrim removes leading and trailing whitespace.
+	NormalizerTrim = "trim"
+	// NormalizerCollapseWhitespace replaces each sequence of whitespace with a single space.
+	NormalizerCollapseWhitespace = "collapse-whitespace"
+	// NormalizerCaseFold ignores differences in capitalisation.
+	NormalizerCaseFold = "case-fold"
+	// NormalizerNFC converts to Unicode normalization form C, so that composed
+	// and decomposed characters are considered equal.
+	NormalizerNFC = "nfc"
+	// NormalizerStripPunctuation removes punctuation.
+	NormalizerStripPunctuation = "strip-punctuation"
+)
+
+// normalizer returns the function that implements the named normalizer.
+func normalizer(name string) (func(string) string, bool) {
+	switch name {
+	case NormalizerTrim:
+		return strings.TrimSpace, true
+	case NormalizerCollapseWhitespace:
+		return collapseWhitespace, true
+	case NormalizerCaseFold:
+		return cases.Fold().String, true
+	case NormalizerNFC:
+		return norm.NFC.String, true
+	case NormalizerStripPunctuation:
+		return stripPunctuation, true
+	default:
+		return nil, false
+	}
+}
+
+// normalize applies the session's normalizers to the answer, in order.
+func (o *SessionOptions) normalize(answer string) string {
+	for _, name := range o.Normalizers {
+		if f, ok := normalizer(name); ok {
+			answer = f(answer)
+		}
+	}
+	return answer
+}
+
+func collapseWhitespace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func stripPunctuation(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsPunct(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
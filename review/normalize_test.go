@@ -0,0 +1,63 @@
+package review
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionOptions_normalize(t *testing.T) {
+	testCases := []struct {
+		id          string
+		normalizers []string
+		answer      string
+		expected    string
+	}{
+		{
+			id:       "No normalizers",
+			answer:   " Hello,  World! ",
+			expected: " Hello,  World! ",
+		},
+		{
+			id:          "Trim",
+			normalizers: []string{NormalizerTrim},
+			answer:      " Hello,  World! ",
+			expected:    "Hello,  World!",
+		},
+		{
+			id:          "Collapse whitespace",
+			normalizers: []string{NormalizerCollapseWhitespace},
+			answer:      " Hello, \t World! ",
+			expected:    "Hello, World!",
+		},
+		{
+			id:          "Case fold",
+			normalizers: []string{NormalizerCaseFold},
+			answer:      "Straße",
+			expected:    "strasse",
+		},
+		{
+			id:          "NFC",
+			normalizers: []string{NormalizerNFC},
+			answer:      "Zu\u0308rich",
+			expected:    "Z\u00fcrich",
+		},
+		{
+			id:          "Strip punctuation",
+			normalizers: []string{NormalizerStripPunctuation},
+			answer:      "Hello, World!",
+			expected:    "Hello World",
+		},
+		{
+			id:          "Chain",
+			normalizers: []string{NormalizerStripPunctuation, NormalizerCollapseWhitespace, NormalizerCaseFold},
+			answer:      " Hello - World! ",
+			expected:    "hello world",
+		},
+	}
+
+	for _, tc := range testCases {
+		options := &SessionOptions{Normalizers: tc.normalizers}
+		require.Equal(t, tc.expected, options.normalize(tc.answer), tc.id)
+	}
+}
@@ -0,0 +1,189 @@
+package review
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Answer types.
+const (
+	// AnswerTypeText means that answers are compared as text.
+	AnswerTypeText = "text"
+	// AnswerTypeNumeric means that answers are numbers, optionally followed by a unit,
+	// which are compared numerically.
+	AnswerTypeNumeric = "numeric"
+)
+
+// quantityPattern splits a quantity like "9.81 m/s²" into its value and unit.
+var quantityPattern = regexp.MustCompile(`^([+-]?(?:\d+\.?\d*|\.\d+)(?:[eE][+-]?\d+)?)\s*(.*)$`)
+
+// numericPrecision is the relative difference below which numbers are
+// considered equal, to allow for rounding errors when converting units.
+const numericPrecision = 1e-9
+
+// siPrefixes are the SI prefixes and their factors. Longer prefixes come first,
+// so that they're preferred when splitting units.
+var siPrefixes = []struct {
+	prefix string
+	factor float64
+}{
+	{prefix: "da", factor: 1e1},
+	{prefix: "Q", factor: 1e30},
+	{prefix: "R", factor: 1e27},
+	{prefix: "Y", factor: 1e24},
+	{prefix: "Z", factor: 1e21},
+	{prefix: "E", factor: 1e18},
+	{prefix: "P", factor: 1e15},
+	{prefix: "T", factor: 1e12},
+	{prefix: "G", factor: 1e9},
+	{prefix: "M", factor: 1e6},
+	{prefix: "k", factor: 1e3},
+	{prefix: "h", factor: 1e2},
+	{prefix: "d", factor: 1e-1},
+	{prefix: "c", factor: 1e-2},
+	{prefix: "m", factor: 1e-3},
+	{prefix: "µ", factor: 1e-6}, // micro sign
+	{prefix: "μ", factor: 1e-6}, // Greek letter mu
+	{prefix: "u", factor: 1e-6},
+	{prefix: "n", factor: 1e-9},
+	{prefix: "p", factor: 1e-12},
+	{prefix: "f", factor: 1e-15},
+	{prefix: "a", factor: 1e-18},
+	{prefix: "z", factor: 1e-21},
+	{prefix: "y", factor: 1e-24},
+	{prefix: "r", factor: 1e-27},
+	{prefix: "q", factor: 1e-30},
+}
+
+// prefixableUnits are the units that may follow an SI prefix: the SI base and
+// derived units, along with a few other units that are commonly prefixed. Units
+// like the tonne ("t") and the day ("d") are left out, since they'd make units
+// like "ft" and "cd" look prefixed.
+var prefixableUnits = []string{
+	"m", "g", "s", "A", "K", "mol", "cd", "Hz", "N", "Pa", "J", "W", "C", "V", "F", "Ω", "S",
+	"Wb", "T", "H", "lm", "lx", "Bq", "Gy", "Sv", "kat", "L", "l", "eV", "B", "bit",
+}
+
+// quantity is a number with an optional unit.
+type quantity struct {
+	value float64
+	unit  string
+}
+
+// parseQuantity parses a number, optionally followed by a unit.
+func parseQuantity(s string) (*quantity, error) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), "−", "-")
+
+	matches := quantityPattern.FindStringSubmatch(s)
+	if matches == nil {
+		return nil, fmt.Errorf("quantity %s: %w", s, ErrInvalidNumber)
+	}
+
+	value, err := strconv.ParseFloat(matches[1], 64)
+	if err != nil {
+		return nil, fmt.Errorf("quantity %s: %w: %w", s, ErrInvalidNumber, err)
+	}
+
+	return &quantity{value: value, unit: matches[2]}, nil
+}
+
+// in returns the value of the quantity in the specified unit, or false if the
+// units are incompatible. A quantity without a unit is assumed to be in the
+// specified unit already. If prefixes may be converted, units that only differ
+// in their SI prefixes (e.g. "cm" and "km") are compatible.
+func (q *quantity) in(unit string, convertPrefixes bool) (float64, bool) {
+	if q.unit == "" || q.unit == unit {
+		return q.value, true
+	}
+
+	if !convertPrefixes {
+		return 0, false
+	}
+
+	for _, from := range prefixedUnits(q.unit) {
+		for _, to := range prefixedUnits(unit) {
+			if from.base == to.base {
+				return q.value * from.factor / to.factor, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+// prefixedUnit is a unit split into an SI prefix and a base unit.
+type prefixedUnit struct {
+	factor float64
+	base   string
+}
+
+// prefixedUnits returns all the ways in which the unit can be split into an
+// SI prefix and a base unit, e.g. "mm" is either milli-metre or just "mm",
+// starting with the unit itself. The prefix must be followed by a prefixable
+// unit, so "min" isn't milli-"in".
+func prefixedUnits(unit string) []prefixedUnit {
+	units := []prefixedUnit{{factor: 1, base: unit}}
+	for _, p := range siPrefixes {
+		if base, ok := strings.CutPrefix(unit, p.prefix); ok && isPrefixable(base) {
+			units = append(units, prefixedUnit{factor: p.factor, base: base})
+		}
+	}
+	return units
+}
+
+// isPrefixable returns true if and only if the unit starts with a prefixable
+// unit, which is either all there is or followed by something other than a
+// letter, e.g. "m" or "m/s²".
+func isPrefixable(unit string) bool {
+	for _, symbol := range prefixableUnits {
+		rest, ok := strings.CutPrefix(unit, symbol)
+		if !ok {
+			continue
+		}
+		r, _ := utf8.DecodeRuneInString(rest)
+		if rest == "" || !unicode.IsLetter(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchNumber compares the answer to the flashcard's numeric answer, accepting
+// any value within the flashcard's absolute or relative tolerance. If the
+// session converts unit prefixes, the value may be given in a differently
+// prefixed unit, e.g. "981 cm/s²" instead of "9.81 m/s²".
+func (f *Flashcard) matchNumber(answer string, session *Session) *Match {
+	expected, err := parseQuantity(f.Metadata.Answer)
+	if err != nil {
+		// Numeric answers are validated when the flashcards are loaded.
+		return &Match{Outcome: OutcomeWrong}
+	}
+
+	submitted, err := parseQuantity(answer)
+	if err != nil {
+		return &Match{Outcome: OutcomeWrong}
+	}
+
+	value, ok := submitted.in(expected.unit, session.ConvertUnitPrefixes)
+	if !ok {
+		return &Match{Outcome: OutcomeWrong}
+	}
+
+	diff := math.Abs(value - expected.value)
+	relativeTolerance := max(f.Metadata.RelativeTolerance, numericPrecision)
+	if diff > f.Metadata.AbsoluteTolerance && diff > relativeTolerance*math.Abs(expected.value) {
+		return &Match{Outcome: OutcomeWrong}
+	}
+
+	match := &Match{Outcome: OutcomeCorrect}
+	if answer != f.Metadata.Answer {
+		match.Answer = f.Metadata.Answer
+	}
+
+	return match
+}
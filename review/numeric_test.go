@@ -0,0 +1,173 @@
+package review
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQuantity(t *testing.T) {
+	testCases := []struct {
+		input       string
+		expected    *quantity
+		expectedErr error
+	}{
+		{input: "42", expected: &quantity{value: 42}},
+		{input: " -1.5e3 ", expected: &quantity{value: -1500}},
+		{input: "−2", expected: &quantity{value: -2}},
+		{input: ".5kg", expected: &quantity{value: 0.5, unit: "kg"}},
+		{input: "9.81 m/s²", expected: &quantity{value: 9.81, unit: "m/s²"}},
+		{input: "m/s²", expectedErr: ErrInvalidNumber},
+		{input: "", expectedErr: ErrInvalidNumber},
+	}
+
+	for _, tc := range testCases {
+		q, err := parseQuantity(tc.input)
+		if tc.expectedErr != nil {
+			require.ErrorIs(t, err, tc.expectedErr, tc.input)
+			continue
+		}
+		require.NoError(t, err, tc.input)
+		require.Equal(t, tc.expected, q, tc.input)
+	}
+}
+
+func TestPrefixedUnits(t *testing.T) {
+	testCases := []struct {
+		unit     string
+		expected []prefixedUnit
+	}{
+		{unit: "km", expected: []prefixedUnit{{factor: 1, base: "km"}, {factor: 1e3, base: "m"}}},
+		{unit: "cm/s²", expected: []prefixedUnit{{factor: 1, base: "cm/s²"}, {factor: 1e-2, base: "m/s²"}}},
+		{unit: "dam", expected: []prefixedUnit{{factor: 1, base: "dam"}, {factor: 1e1, base: "m"}}},
+		{unit: "min", expected: []prefixedUnit{{factor: 1, base: "min"}}},
+		{unit: "ft", expected: []prefixedUnit{{factor: 1, base: "ft"}}},
+		{unit: "cd", expected: []prefixedUnit{{factor: 1, base: "cd"}}},
+		{unit: "mol", expected: []prefixedUnit{{factor: 1, base: "mol"}}},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.expected, prefixedUnits(tc.unit), tc.unit)
+	}
+}
+
+func TestFlashcard_match_numeric(t *testing.T) {
+	testCases := []struct {
+		id       string
+		metadata FlashcardMetadata
+		answer   string
+		options  SessionOptions
+		match    *Match
+	}{
+		{
+			id:       "Exact",
+			metadata: FlashcardMetadata{Answer: "9.81 m/s²"},
+			answer:   "9.81 m/s²",
+			match:    &Match{Outcome: OutcomeCorrect},
+		},
+		{
+			id:       "Different notation",
+			metadata: FlashcardMetadata{Answer: "9.81 m/s²"},
+			answer:   "9.810m/s²",
+			match:    &Match{Outcome: OutcomeCorrect, Answer: "9.81 m/s²"},
+		},
+		{
+			id:       "Without unit",
+			metadata: FlashcardMetadata{Answer: "9.81 m/s²"},
+			answer:   "9.81",
+			match:    &Match{Outcome: OutcomeCorrect, Answer: "9.81 m/s²"},
+		},
+		{
+			id:       "No tolerance",
+			metadata: FlashcardMetadata{Answer: "9.81 m/s²"},
+			answer:   "9.8 m/s²",
+			match:    &Match{Outcome: OutcomeWrong},
+		},
+		{
+			id:       "Within absolute tolerance",
+			metadata: FlashcardMetadata{Answer: "9.81 m/s²", AbsoluteTolerance: 0.02},
+			answer:   "9.8 m/s²",
+			match:    &Match{Outcome: OutcomeCorrect, Answer: "9.81 m/s²"},
+		},
+		{
+			id:       "Outside absolute tolerance",
+			metadata: FlashcardMetadata{Answer: "9.81 m/s²", AbsoluteTolerance: 0.005},
+			answer:   "9.8 m/s²",
+			match:    &Match{Outcome: OutcomeWrong},
+		},
+		{
+			id:       "Within relative tolerance",
+			metadata: FlashcardMetadata{Answer: "300000 km/s", RelativeTolerance: 0.01},
+			answer:   "299792 km/s",
+			match:    &Match{Outcome: OutcomeCorrect, Answer: "300000 km/s"},
+		},
+		{
+			id:       "Different unit prefix",
+			metadata: FlashcardMetadata{Answer: "9.81 m/s²"},
+			answer:   "981 cm/s²",
+			match:    &Match{Outcome: OutcomeWrong},
+		},
+		{
+			id:       "Converted unit prefix",
+			metadata: FlashcardMetadata{Answer: "9.81 m/s²"},
+			answer:   "981 cm/s²",
+			options:  SessionOptions{ConvertUnitPrefixes: true},
+			match:    &Match{Outcome: OutcomeCorrect, Answer: "9.81 m/s²"},
+		},
+		{
+			id:       "Converted to prefixed unit",
+			metadata: FlashcardMetadata{Answer: "1.5 km"},
+			answer:   "1500 m",
+			options:  SessionOptions{ConvertUnitPrefixes: true},
+			match:    &Match{Outcome: OutcomeCorrect, Answer: "1.5 km"},
+		},
+		{
+			id:       "Converted between prefixes",
+			metadata: FlashcardMetadata{Answer: "2 µF"},
+			answer:   "2000 nF",
+			options:  SessionOptions{ConvertUnitPrefixes: true},
+			match:    &Match{Outcome: OutcomeCorrect, Answer: "2 µF"},
+		},
+		{
+			id:       "Incompatible unit",
+			metadata: FlashcardMetadata{Answer: "60 s"},
+			answer:   "1 min",
+			options:  SessionOptions{ConvertUnitPrefixes: true},
+			match:    &Match{Outcome: OutcomeWrong},
+		},
+		{
+			id:       "Not a prefixed unit",
+			metadata: FlashcardMetadata{Answer: "2 in"},
+			answer:   "2000 min",
+			options:  SessionOptions{ConvertUnitPrefixes: true},
+			match:    &Match{Outcome: OutcomeWrong},
+		},
+		{
+			id:       "Not a prefixed unit either",
+			metadata: FlashcardMetadata{Answer: "3 t"},
+			answer:   "3e15 ft",
+			options:  SessionOptions{ConvertUnitPrefixes: true},
+			match:    &Match{Outcome: OutcomeWrong},
+		},
+		{
+			id:       "Prefixed unit with a longer symbol",
+			metadata: FlashcardMetadata{Answer: "2 mol"},
+			answer:   "2000 mmol",
+			options:  SessionOptions{ConvertUnitPrefixes: true},
+			match:    &Match{Outcome: OutcomeCorrect, Answer: "2 mol"},
+		},
+		{
+			id:       "Not a number",
+			metadata: FlashcardMetadata{Answer: "42"},
+			answer:   "forty-two",
+			match:    &Match{Outcome: OutcomeWrong},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc.metadata.AnswerType = AnswerTypeNumeric
+		f := &Flashcard{Metadata: tc.metadata}
+		session := &Session{SessionOptions: tc.options}
+		require.Equal(t, tc.match, f.match(tc.answer, session), tc.id)
+	}
+}
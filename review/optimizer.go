@@ -0,0 +1,179 @@
+package review
+
+import (
+	"errors"
+	"math"
+	"slices"
+)
+
+const (
+	optimizerMaxIterations = 500
+	optimizerInitialStep   = 0.1
+	optimizerMinStep       = 1e-4
+	optimizerPrecision     = 1e4
+	optimizerEpsilon       = 1e-6
+)
+
+// ErrInsufficientHistory is thrown if there isn't enough review history to fit a model.
+var ErrInsufficientHistory = errors.New("insufficient review history")
+
+// fsrsBounds are the lower and upper bounds for each of the FSRS model weights.
+var fsrsBounds = [][2]float64{
+	{0.1, 100}, {0.1, 100}, {0.1, 100}, {0.1, 100},
+	{1, 10}, {0.001, 4}, {0.001, 4}, {0.001, 0.75},
+	{0, 4.5}, {0, 0.8}, {0.001, 3.5}, {0.001, 5},
+	{0.001, 0.25}, {0.001, 0.9}, {0, 4}, {0, 1},
+	{1, 6},
+}
+
+// isValidFSRSWeights returns true if and only if there's a weight for each
+// parameter of the FSRS model and each weight is within its bounds.
+func isValidFSRSWeights(weights []float64) bool {
+	if len(weights) != len(fsrsBounds) {
+		return false
+	}
+
+	for i, bounds := range fsrsBounds {
+		if math.IsNaN(weights[i]) || weights[i] < bounds[0] || weights[i] > bounds[1] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// historyEntry records the outcome of a single review of a flashcard.
+type historyEntry struct {
+	// elapsed is the number of rounds (or days) since the previous review (0 for the first review).
+	elapsed int
+	// rating rates the recall from 1 (forgotten) to 4 (easy).
+	rating Grade
+}
+
+// OptimizeFSRS fits the FSRS model weights to a session's review log, starting
+// from the specified weights. Every correct answer counts as a review, and the
+// time elapsed between reviews is measured in rounds, or in days for time-based
+// sessions. The fitted weights minimize the log-loss of the predicted probability
+// of recall, i.e. how surprising the outcome of each review (other than the
+// first) was.
+func OptimizeFSRS(log []*ReviewLogEntry, timeBased bool, weights []float64) ([]float64, error) {
+	histories := reviewHistories(log, timeBased)
+
+	var numPredictions int
+	for _, history := range histories {
+		numPredictions += len(history) - 1
+	}
+
+	if numPredictions == 0 {
+		return nil, ErrInsufficientHistory
+	}
+
+	best := make(fsrsModel, len(fsrsBounds))
+	for i, bounds := range fsrsBounds {
+		best[i] = DefaultFSRSWeights[i]
+		if len(weights) == len(fsrsBounds) {
+			best[i] = weights[i]
+		}
+		best[i] = min(max(best[i], bounds[0]), bounds[1])
+	}
+	bestLoss := best.logLoss(histories)
+
+	// We use a simple pattern search, which doesn't require any gradients:
+	// nudge one weight at a time and keep the change if the loss improves,
+	// otherwise shrink the step size until the weights settle.
+	step := optimizerInitialStep
+	for range optimizerMaxIterations {
+		if step < optimizerMinStep {
+			break
+		}
+
+		improved := false
+		for i, bounds := range fsrsBounds {
+			delta := step * (bounds[1] - bounds[0])
+			for _, candidate := range []float64{best[i] + delta, best[i] - delta} {
+				model := slices.Clone(best)
+				model[i] = min(max(candidate, bounds[0]), bounds[1])
+				loss := model.logLoss(histories)
+				if loss < bestLoss-optimizerEpsilon {
+					best, bestLoss = model, loss
+					improved = true
+					break
+				}
+			}
+		}
+
+		if !improved {
+			step /= 2
+		}
+	}
+
+	for i := range best {
+		best[i] = math.Round(best[i]*optimizerPrecision) / optimizerPrecision
+	}
+
+	return best, nil
+}
+
+// reviewHistories replays the review log, which is ordered from oldest to
+// newest, and returns the review history of each flashcard that has been
+// reviewed more than once.
+func reviewHistories(log []*ReviewLogEntry, timeBased bool) [][]historyEntry {
+	historiesByID := make(map[int64][]historyEntry)
+	lastReviews := make(map[int64]*ReviewLogEntry)
+
+	var ids []int64
+	for _, entry := range log {
+		if !entry.IsCorrect {
+			continue
+		}
+
+		var elapsed int
+		last, ok := lastReviews[entry.FlashcardID]
+		switch {
+		case !ok:
+			ids = append(ids, entry.FlashcardID)
+		case timeBased:
+			elapsed = int(entry.Time.Sub(last.Time) / day)
+		default:
+			elapsed = entry.Round - last.Round
+		}
+
+		historiesByID[entry.FlashcardID] = append(historiesByID[entry.FlashcardID], historyEntry{elapsed: elapsed, rating: entry.Grade})
+		lastReviews[entry.FlashcardID] = entry
+	}
+
+	// We intentionally don't preallocate the slice, because we don't know how
+	// many flashcards have been reviewed more than once.
+	var histories [][]historyEntry //nolint:prealloc
+	for _, id := range ids {
+		if len(historiesByID[id]) > 1 {
+			histories = append(histories, historiesByID[id])
+		}
+	}
+
+	return histories
+}
+
+// logLoss replays the review histories and returns the mean log-loss of the
+// probability of recall predicted before each review (other than the first).
+func (w fsrsModel) logLoss(histories [][]historyEntry) float64 {
+	var loss float64
+	var count int
+
+	for _, history := range histories {
+		stability, difficulty := w.initialState(history[0].rating)
+		for _, entry := range history[1:] {
+			r := retrievability(float64(entry.elapsed), stability)
+			r = min(max(r, optimizerEpsilon), 1-optimizerEpsilon)
+			if entry.rating == GradeAgain {
+				loss -= math.Log(1 - r)
+			} else {
+				loss -= math.Log(r)
+			}
+			count++
+			stability, difficulty = w.nextState(stability, difficulty, float64(entry.elapsed), entry.rating)
+		}
+	}
+
+	return loss / float64(count)
+}
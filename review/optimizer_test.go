@@ -0,0 +1,113 @@
+package review
+
+import (
+	"context"
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptimizeFSRS(t *testing.T) {
+	var log []*ReviewLogEntry
+	for i := int64(1); i <= 10; i++ {
+		rounds := []int{0, 10, 50, 170}
+		grades := []Grade{GradeGood, GradeGood, GradeGood, GradeGood}
+		if i%5 == 0 {
+			rounds = append(rounds, 470)
+			grades = append(grades, GradeAgain)
+		}
+		for j := range rounds {
+			log = append(log, &ReviewLogEntry{FlashcardID: i, IsCorrect: true, Grade: grades[j], Round: rounds[j]})
+		}
+	}
+
+	histories := reviewHistories(log, false)
+
+	weights, err := OptimizeFSRS(log, false, DefaultFSRSWeights)
+	require.NoError(t, err)
+	require.Len(t, weights, len(DefaultFSRSWeights))
+	require.True(t, isValidFSRSWeights(weights))
+
+	defaultLoss := fsrsModel(DefaultFSRSWeights).logLoss(histories)
+	fittedLoss := fsrsModel(weights).logLoss(histories)
+	require.Less(t, fittedLoss, defaultLoss)
+}
+
+func TestOptimizeFSRS_insufficientHistory(t *testing.T) {
+	log := []*ReviewLogEntry{
+		{FlashcardID: 1, IsCorrect: true, Grade: GradeGood},
+		{FlashcardID: 2, Answer: "x"},
+		{FlashcardID: 2, IsCorrect: true, Grade: GradeAgain},
+	}
+
+	_, err := OptimizeFSRS(log, false, nil)
+	require.ErrorIs(t, err, ErrInsufficientHistory)
+}
+
+func Test_reviewHistories(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	log := []*ReviewLogEntry{
+		{FlashcardID: 1, IsCorrect: true, Grade: GradeGood, Round: 0, Time: start},
+		{FlashcardID: 2, IsCorrect: true, Grade: GradeGood, Round: 0, Time: start},
+		{FlashcardID: 1, Answer: "x", Round: 2, Time: start.Add(36 * time.Hour)},
+		{FlashcardID: 1, IsCorrect: true, Grade: GradeAgain, Round: 2, Time: start.Add(36 * time.Hour)},
+		{FlashcardID: 1, IsCorrect: true, Grade: GradeEasy, Round: 3, Time: start.Add(96 * time.Hour)},
+	}
+
+	require.Equal(t, [][]historyEntry{{
+		{elapsed: 0, rating: GradeGood},
+		{elapsed: 2, rating: GradeAgain},
+		{elapsed: 1, rating: GradeEasy},
+	}}, reviewHistories(log, false))
+
+	require.Equal(t, [][]historyEntry{{
+		{elapsed: 0, rating: GradeGood},
+		{elapsed: 1, rating: GradeAgain},
+		{elapsed: 2, rating: GradeEasy},
+	}}, reviewHistories(log, true))
+}
+
+func Test_isValidFSRSWeights(t *testing.T) {
+	require.True(t, isValidFSRSWeights(DefaultFSRSWeights))
+	require.False(t, isValidFSRSWeights(DefaultFSRSWeights[1:]))
+
+	weights := slices.Clone(DefaultFSRSWeights)
+	weights[0] = -1
+	require.False(t, isValidFSRSWeights(weights))
+
+	weights[0] = math.NaN()
+	require.False(t, isValidFSRSWeights(weights))
+}
+
+func TestReviewer_OptimizeFSRS(t *testing.T) {
+	ctx := context.Background()
+
+	r := NewReviewer(NewMemoryStore(), NewFSRSScheduler())
+
+	session, err := r.CreateSession(ctx, newMemorySource(2), 3, &SessionOptions{})
+	require.NoError(t, err)
+
+	_, err = r.OptimizeFSRS(ctx, session.ID)
+	require.ErrorIs(t, err, ErrInsufficientHistory)
+
+	for i := range 20 {
+		f, err := r.NextFlashcard(ctx, session.ID)
+		require.NoError(t, err, i)
+
+		submission := &Submission{Answer: f.Metadata.Answer, IsFirstGuess: i%3 != 0}
+		_, err = r.Submit(ctx, session.ID, f.Metadata.ID, submission)
+		require.NoError(t, err, i)
+	}
+
+	optimizedSession, err := r.OptimizeFSRS(ctx, session.ID)
+	require.NoError(t, err)
+	require.Len(t, optimizedSession.FSRSWeights, len(DefaultFSRSWeights))
+
+	storedSession, err := r.GetSession(ctx, session.ID)
+	require.NoError(t, err)
+	require.Equal(t, optimizedSession.FSRSWeights, storedSession.FSRSWeights)
+}
@@ -0,0 +1,86 @@
+package review
+
+import (
+	"cmp"
+	"encoding/binary"
+	"hash/fnv"
+	"slices"
+)
+
+// Ordering strategies, which decide which flashcard is reviewed next if
+// several are available.
+const (
+	// OrderingByID prefers flashcards with lower IDs.
+	OrderingByID = "id"
+	// OrderingRandom shuffles the flashcards in a way that is reproducible for a given seed.
+	OrderingRandom = "random"
+	// OrderingOverdue prefers the flashcards that have been due for the longest time.
+	OrderingOverdue = "overdue"
+	// OrderingProficiency prefers the flashcards with the fewest successful reviews in a row.
+	OrderingProficiency = "proficiency"
+	// OrderingInterleaved alternates between new and previously reviewed flashcards,
+	// preferring lower IDs within each group.
+	OrderingInterleaved = "interleaved"
+)
+
+// isValidOrdering returns true if and only if the ordering is one of the
+// defined orderings or empty, in which case the store's own order is used.
+func isValidOrdering(ordering string) bool {
+	switch ordering {
+	case "", OrderingByID, OrderingRandom, OrderingOverdue, OrderingProficiency, OrderingInterleaved:
+		return true
+	default:
+		return false
+	}
+}
+
+// pick returns the candidate that should be reviewed next according to the
+// query's ordering. If no ordering is specified, the first candidate is picked.
+func (q *ReviewQuery) pick(candidates []*Flashcard) (*Flashcard, error) {
+	if len(candidates) == 0 {
+		return nil, ErrNotFound
+	}
+
+	if q.Ordering == "" {
+		return candidates[0], nil
+	}
+
+	return slices.MinFunc(candidates, q.compare), nil
+}
+
+// compare orders flashcards according to the query's ordering, falling back
+// to the flashcard ID to break ties.
+func (q *ReviewQuery) compare(a, b *Flashcard) int {
+	var c int
+
+	switch q.Ordering {
+	case OrderingRandom:
+		c = cmp.Compare(q.shuffleKey(a), q.shuffleKey(b))
+	case OrderingOverdue:
+		if q.TimeBased {
+			c = a.Stats.DueAt.Compare(b.Stats.DueAt)
+		} else {
+			c = cmp.Compare(a.Stats.NextReview, b.Stats.NextReview)
+		}
+	case OrderingProficiency:
+		c = cmp.Compare(a.Stats.Repetitions, b.Stats.Repetitions)
+	}
+
+	return cmp.Or(c, cmp.Compare(a.Metadata.ID, b.Metadata.ID))
+}
+
+// shuffleKey returns a pseudo-random sort key for the flashcard, which depends
+// on the seed, the current round (or day) and how often the flashcard has been
+// reviewed, so that the order changes over time but is still reproducible.
+func (q *ReviewQuery) shuffleKey(f *Flashcard) uint64 {
+	return hashKey(q.Seed, int64(q.period()), f.Metadata.ID, int64(f.Stats.ViewCount))
+}
+
+// hashKey deterministically combines the values into a pseudo-random number.
+func hashKey(values ...int64) uint64 {
+	h := fnv.New64a()
+	for _, v := range values {
+		_, _ = h.Write(binary.LittleEndian.AppendUint64(nil, uint64(v)))
+	}
+	return h.Sum64()
+}
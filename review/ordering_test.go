@@ -0,0 +1,85 @@
+package review
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReviewQuery_pick(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	candidates := []*Flashcard{
+		{Metadata: flashcardMetadata(3), Stats: FlashcardStats{Repetitions: 2, NextReview: 1, DueAt: now}},
+		{Metadata: flashcardMetadata(1), Stats: FlashcardStats{Repetitions: 1, NextReview: 2, DueAt: now.Add(-day)}},
+		{Metadata: flashcardMetadata(2), Stats: FlashcardStats{Repetitions: 1, NextReview: 3, DueAt: now.Add(-2 * day)}},
+	}
+
+	testCases := []struct {
+		id         string
+		query      *ReviewQuery
+		expectedID int64
+	}{
+		{
+			id:         "Store order",
+			query:      &ReviewQuery{},
+			expectedID: 3,
+		},
+		{
+			id:         "By ID",
+			query:      &ReviewQuery{Ordering: OrderingByID},
+			expectedID: 1,
+		},
+		{
+			id:         "Most overdue",
+			query:      &ReviewQuery{Ordering: OrderingOverdue},
+			expectedID: 3,
+		},
+		{
+			id:         "Most overdue (time-based)",
+			query:      &ReviewQuery{Ordering: OrderingOverdue, TimeBased: true, Now: now},
+			expectedID: 2,
+		},
+		{
+			id:         "Lowest proficiency",
+			query:      &ReviewQuery{Ordering: OrderingProficiency},
+			expectedID: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.id, func(t *testing.T) {
+			f, err := tc.query.pick(candidates)
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedID, f.Metadata.ID)
+		})
+	}
+
+	_, err := (&ReviewQuery{}).pick(nil)
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestReviewQuery_pick_random(t *testing.T) {
+	var candidates []*Flashcard
+	for i := 1; i <= 10; i++ {
+		candidates = append(candidates, &Flashcard{Metadata: flashcardMetadata(i)})
+	}
+
+	picked := make(map[int64]bool)
+
+	for seed := range int64(20) {
+		query := &ReviewQuery{Ordering: OrderingRandom, Seed: seed}
+
+		f, err := query.pick(candidates)
+		require.NoError(t, err)
+
+		again, err := query.pick(candidates)
+		require.NoError(t, err)
+		require.Same(t, f, again)
+
+		picked[f.Metadata.ID] = true
+	}
+
+	require.Greater(t, len(picked), 1)
+}
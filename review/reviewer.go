@@ -2,9 +2,14 @@
 package review
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -12,30 +17,109 @@ import (
 var (
 	// ErrAmbiguousAnswers is thrown if a flashcard has contradictory answers.
 	ErrAmbiguousAnswers = errors.New("answers are ambiguous")
+	// ErrInvalidChoice is thrown if the answer submitted in a multiple-choice session isn't one of the choices.
+	ErrInvalidChoice = errors.New("invalid choice")
+	// ErrInvalidGrade is thrown if a submission has an unknown grade.
+	ErrInvalidGrade = errors.New("invalid grade")
+	// ErrInvalidPattern is thrown if a flashcard's answer pattern isn't a valid regular expression.
+	ErrInvalidPattern = errors.New("pattern is invalid")
+	// ErrInvalidNumber is thrown if a numeric answer or tolerance isn't a valid number.
+	ErrInvalidNumber = errors.New("number is invalid")
+	// ErrInvalidOptions is thrown if the session options are invalid.
+	ErrInvalidOptions = errors.New("invalid session options")
+	// ErrNotGradable is thrown if a flashcard is graded in a multiple-choice session instead of picking a choice.
+	ErrNotGradable = errors.New("grading not allowed")
+	// ErrNotRevealed is thrown if a flashcard is graded in reveal mode without revealing the answer first.
+	ErrNotRevealed = errors.New("answer not revealed")
+	// ErrNothingDue is thrown if no flashcards are due to be reviewed yet (time-based sessions only)
+	// or if every flashcard is suspended.
+	ErrNothingDue = errors.New("no flashcards are due")
+	// ErrNothingToUndo is thrown if no submissions have changed the session state yet.
+	ErrNothingToUndo = errors.New("nothing to undo")
+	// ErrLimitReached is thrown if flashcards are still due, but the session's limit on reviews
+	// for the current round (or day, for time-based sessions) has been reached.
+	ErrLimitReached = errors.New("review limit reached")
 	// ErrNotFound is thrown if the specified data isn't found.
 	ErrNotFound = errors.New("not found")
+	// ErrUnknownAnswerType is thrown if a flashcard has an answer type that isn't supported.
+	ErrUnknownAnswerType = errors.New("unknown answer type")
+	// ErrUnknownScheduler is thrown if a session uses a scheduler that isn't available.
+	ErrUnknownScheduler = errors.New("unknown scheduler")
 )
 
 // Reviewer manages flashcard review sessions.
 type Reviewer struct {
-	store SessionStore
+	store            SessionStore
+	schedulers       map[string]Scheduler
+	defaultScheduler string
+	now              func() time.Time
 }
 
-// NewReviewer returns a new flashcard reviewer.
-func NewReviewer(store SessionStore) *Reviewer {
-	return &Reviewer{store: store}
+// NewReviewer returns a new flashcard reviewer. New sessions will use the
+// specified scheduler to decide when flashcards are due to be reviewed, unless
+// one of the alternative schedulers is selected when creating the session.
+func NewReviewer(store SessionStore, scheduler Scheduler, alternatives ...Scheduler) *Reviewer {
+	schedulers := map[string]Scheduler{scheduler.Name(): scheduler}
+	for _, s := range alternatives {
+		schedulers[s.Name()] = s
+	}
+
+	return &Reviewer{
+		store:            store,
+		schedulers:       schedulers,
+		defaultScheduler: scheduler.Name(),
+		now:              time.Now,
+	}
+}
+
+// SetClock overrides the function used to determine the current time.
+func (r *Reviewer) SetClock(now func() time.Time) {
+	r.now = now
 }
 
 // CreateSession creates a new session with all flashcards marked as unreviewed.
-func (r *Reviewer) CreateSession(ctx context.Context, source FlashcardMetadataSource, numProficiencyLevels int) (*Session, error) {
+// If no scheduler is specified in the options, the default scheduler is used.
+func (r *Reviewer) CreateSession(
+	ctx context.Context,
+	source FlashcardMetadataSource,
+	numProficiencyLevels int,
+	options *SessionOptions,
+) (*Session, error) {
 	sessionID := uuid.NewString()
 
-	flashcardMetadata, err := getFlashcardMetadata(ctx, source)
+	opts := *options
+	if opts.Scheduler == "" {
+		opts.Scheduler = r.defaultScheduler
+	}
+
+	err := validateOptions(&opts)
 	if err != nil {
 		return nil, err
 	}
 
+	needsSeed := opts.Ordering == OrderingRandom || opts.IntervalFuzz > 0 || opts.ReviewMode == ReviewModeMultipleChoice
+	if needsSeed && opts.Seed == 0 {
+		opts.Seed = rand.Int64()
+	}
+
+	// With the Leitner system, each proficiency level corresponds to a box.
+	if opts.Scheduler == LeitnerSchedulerName {
+		numProficiencyLevels = len(opts.boxCadences())
+	}
+
 	session := NewSession(sessionID, numProficiencyLevels)
+	session.SessionOptions = opts
+
+	_, err = r.scheduler(session)
+	if err != nil {
+		return nil, err
+	}
+
+	flashcardMetadata, err := getFlashcardMetadata(ctx, source, &opts)
+	if err != nil {
+		return nil, err
+	}
+
 	session.UnreviewedCount = len(flashcardMetadata)
 
 	err = r.store.SetSession(ctx, sessionID, session)
@@ -61,9 +145,44 @@ func (r *Reviewer) GetSession(ctx context.Context, sessionID string) (*Session,
 	return r.store.GetSession(ctx, sessionID)
 }
 
-// GetFlashcards returns all flashcards.
+// GetFlashcards returns all flashcards. Like NextFlashcard, it omits the
+// answers in reveal mode (unless they've already been revealed) and in
+// multiple-choice mode.
 func (r *Reviewer) GetFlashcards(ctx context.Context, sessionID string) ([]*Flashcard, error) {
-	return r.store.GetFlashcards(ctx, sessionID)
+	session, err := r.store.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	flashcards, err := r.store.GetFlashcards(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	visible := make([]*Flashcard, 0, len(flashcards))
+	for _, f := range flashcards {
+		visible = append(visible, f.visible(session))
+	}
+
+	return visible, nil
+}
+
+// GetLeeches returns all flashcards that have been marked as leeches, omitting
+// the answers in the same way as GetFlashcards.
+func (r *Reviewer) GetLeeches(ctx context.Context, sessionID string) ([]*Flashcard, error) {
+	flashcards, err := r.GetFlashcards(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	leeches := []*Flashcard{}
+	for _, f := range flashcards {
+		if f.Stats.IsLeech {
+			leeches = append(leeches, f)
+		}
+	}
+
+	return leeches, nil
 }
 
 // SyncFlashcards ensures that the session data is up to date with the flashcard metadata source.
@@ -73,7 +192,7 @@ func (r *Reviewer) SyncFlashcards(ctx context.Context, sessionID string, source
 		return nil, err
 	}
 
-	flashcardMetadata, err := getFlashcardMetadata(ctx, source)
+	flashcardMetadata, err := getFlashcardMetadata(ctx, source, &session.SessionOptions)
 	if err != nil {
 		return nil, err
 	}
@@ -104,66 +223,333 @@ func (r *Reviewer) SyncFlashcards(ctx context.Context, sessionID string, source
 }
 
 // NextFlashcard returns the next flashcard to be reviewed.
+// In reveal mode, the answer is omitted unless it has already been revealed.
+// In multiple-choice mode, the answer is omitted, but the stats include the
+// choices, one of which is the answer.
 func (r *Reviewer) NextFlashcard(ctx context.Context, sessionID string) (*Flashcard, error) {
 	session, err := r.store.GetSession(ctx, sessionID)
 	if err != nil {
 		return nil, err
 	}
 
-	if session.IsNewRound {
-		f, err := r.store.NextUnreviewed(ctx, sessionID)
+	f, err := r.nextFlashcard(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.isMultipleChoice(session) {
+		err = r.offerChoices(ctx, session, f)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return f.visible(session), nil
+}
+
+// offerChoices picks the choices for a multiple-choice review of the
+// flashcard, unless they've already been picked. The distractors are drawn from
+// a bounded number of flashcards, so that large collections aren't read in full.
+func (r *Reviewer) offerChoices(ctx context.Context, session *Session, f *Flashcard) error {
+	if len(f.Stats.Choices) > 0 {
+		return nil
+	}
+
+	candidates, err := r.store.GetChoiceCandidates(ctx, session.ID, f.Metadata.Context, maxChoiceCandidates)
+	if err != nil {
+		return err
+	}
+
+	f.Stats.Choices = f.choices(candidates, session.numChoices(), session.Seed)
+
+	return r.store.SetFlashcardStats(ctx, session.ID, f.Metadata.ID, &f.Stats)
+}
+
+// Reveal records that the answer to a flashcard has been revealed and returns
+// the flashcard, including the answer.
+func (r *Reviewer) Reveal(ctx context.Context, sessionID string, flashcardID int64) (*Flashcard, error) {
+	f, err := r.store.GetFlashcard(ctx, sessionID, flashcardID)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.Stats.Revealed {
+		return f, nil
+	}
+
+	f.Stats.Revealed = true
+
+	err = r.store.SetFlashcardStats(ctx, sessionID, flashcardID, &f.Stats)
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Suspend excludes a flashcard from reviews until it's unsuspended.
+func (r *Reviewer) Suspend(ctx context.Context, sessionID string, flashcardID int64) (*Flashcard, error) {
+	session, err := r.store.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.store.SetSuspended(ctx, sessionID, flashcardID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.visibleFlashcard(ctx, session, flashcardID)
+}
+
+// Unsuspend includes a suspended flashcard in reviews again.
+func (r *Reviewer) Unsuspend(ctx context.Context, sessionID string, flashcardID int64) (*Flashcard, error) {
+	session, err := r.store.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.store.SetSuspended(ctx, sessionID, flashcardID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.visibleFlashcard(ctx, session, flashcardID)
+}
+
+// Bury excludes a flashcard from reviews until the next round (or day, for
+// time-based sessions).
+func (r *Reviewer) Bury(ctx context.Context, sessionID string, flashcardID int64) (*Flashcard, error) {
+	session, err := r.store.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.store.SetBuriedUntil(ctx, sessionID, flashcardID, session.period(r.now())+1)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.visibleFlashcard(ctx, session, flashcardID)
+}
+
+// visibleFlashcard returns the specified flashcard without giving away any
+// answers that the session's review mode hides.
+func (r *Reviewer) visibleFlashcard(ctx context.Context, session *Session, flashcardID int64) (*Flashcard, error) {
+	f, err := r.store.GetFlashcard(ctx, session.ID, flashcardID)
+	if err != nil {
+		return nil, err
+	}
+	return f.visible(session), nil
+}
+
+func (r *Reviewer) nextFlashcard(ctx context.Context, session *Session) (*Flashcard, error) {
+	now := r.now()
+	session.resetCounts(session.period(now))
+
+	query := session.reviewQuery(now)
+
+	lookups := []func() (*Flashcard, error){
+		func() (*Flashcard, error) { return r.nextUnreviewed(ctx, session, query) },
+		func() (*Flashcard, error) { return r.nextReviewed(ctx, session, query) },
+	}
+
+	if session.prefersReviewed() {
+		slices.Reverse(lookups)
+	}
+
+	for _, lookup := range lookups {
+		f, err := lookup()
 		if !errors.Is(err, ErrNotFound) {
 			return f, err
 		}
 	}
 
-	f, err := r.store.NextReviewed(ctx, sessionID, session.Round)
-	if !errors.Is(err, ErrNotFound) {
-		return f, err
+	// Once the limit on reviews has been reached, any flashcards that are still
+	// due have to wait until the next round is started explicitly (or until the
+	// next day, for time-based sessions).
+	if !session.allowsReview() {
+		_, err := r.store.NextReviewed(ctx, session.ID, query)
+		if err == nil {
+			return nil, ErrLimitReached
+		}
+		if !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+	}
+
+	// For time-based sessions, starting a new round doesn't make any more
+	// flashcards due, so there's no point in starting another one.
+	if session.TimeBased && session.IsNewRound {
+		return nil, ErrNothingDue
+	}
+
+	// If every flashcard is suspended, no number of rounds will make any of
+	// them due, so we'd be looping forever. Buried flashcards are fine, since
+	// they'll be available again in the next round.
+	if session.IsNewRound {
+		suspended, err := r.store.CountSuspended(ctx, session.ID)
+		if err != nil {
+			return nil, err
+		}
+		if suspended >= session.flashcardCount() {
+			return nil, ErrNothingDue
+		}
 	}
 
+	err := r.startRound(ctx, session, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.nextFlashcard(ctx, session)
+}
+
+// NextRound starts the next round, so that reviews can continue once the
+// session's limit on reviews for the current round has been reached. For
+// time-based sessions, the limits apply per day, so they're unaffected.
+func (r *Reviewer) NextRound(ctx context.Context, sessionID string) (*Session, error) {
+	session, err := r.store.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.startRound(ctx, session, r.now())
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+func (r *Reviewer) startRound(ctx context.Context, session *Session, now time.Time) error {
 	session.Round++
 	session.IsNewRound = true
+	session.resetCounts(session.period(now))
 
-	err = r.store.SetSession(ctx, sessionID, session)
+	return r.store.SetSession(ctx, session.ID, session)
+}
+
+// nextUnreviewed returns a new flashcard, unless the session's limit on new
+// flashcards has been reached.
+func (r *Reviewer) nextUnreviewed(ctx context.Context, session *Session, query *ReviewQuery) (*Flashcard, error) {
+	if !session.allowsNew() {
+		return nil, ErrNotFound
+	}
+	return r.store.NextUnreviewed(ctx, session.ID, query)
+}
+
+// nextReviewed returns a previously reviewed flashcard that is due, unless the
+// session's limit on reviews has been reached.
+func (r *Reviewer) nextReviewed(ctx context.Context, session *Session, query *ReviewQuery) (*Flashcard, error) {
+	if !session.allowsReview() {
+		return nil, ErrNotFound
+	}
+	return r.store.NextReviewed(ctx, session.ID, query)
+}
+
+// Grade updates the session state following the review of a flashcard that
+// was graded by the user, without checking any answer. In reveal mode, the
+// answer must have been revealed first.
+func (r *Reviewer) Grade(ctx context.Context, sessionID string, flashcardID int64, grade Grade) (*Session, error) {
+	if !grade.isValid() {
+		return nil, fmt.Errorf("grade %d: %w", grade, ErrInvalidGrade)
+	}
+
+	submission := &Submission{IsFirstGuess: true, Grade: grade, selfGraded: true}
+
+	result, err := r.Submit(ctx, sessionID, flashcardID, submission)
 	if err != nil {
 		return nil, err
 	}
 
-	return r.NextFlashcard(ctx, sessionID)
+	return result.Session, nil
+}
+
+// SubmitResult describes the outcome of a submission.
+type SubmitResult struct {
+	// Session is the updated session.
+	Session *Session `json:"session"`
+	// Stats are the flashcard's updated stats.
+	Stats FlashcardStats `json:"stats"`
+	// IsCorrect is true if and only if the answer was accepted.
+	IsCorrect bool `json:"isCorrect"`
+	// Match describes how the answer compares to the expected answer.
+	*Match
 }
 
 // Submit updates the session state following the review of a flashcard.
-func (r *Reviewer) Submit(ctx context.Context, sessionID string, flashcardID int64, submission *Submission) (*Session, bool, error) {
+// Incorrect answers are recorded in the flashcard's stats, but don't affect the
+// session state.
+func (r *Reviewer) Submit(ctx context.Context, sessionID string, flashcardID int64, submission *Submission) (*SubmitResult, error) {
+	if submission.Grade != 0 && !submission.Grade.isValid() {
+		return nil, fmt.Errorf("grade %d: %w", submission.Grade, ErrInvalidGrade)
+	}
+
 	session, err := r.store.GetSession(ctx, sessionID)
 	if err != nil {
-		return nil, false, err
+		return nil, err
+	}
+
+	scheduler, err := r.scheduler(session)
+	if err != nil {
+		return nil, err
 	}
 
 	f, err := r.store.GetFlashcard(ctx, sessionID, flashcardID)
 	if err != nil {
-		return nil, false, err
+		return nil, err
 	}
 
-	previousViewCount := f.Stats.ViewCount
-	previousRepetitions := f.Stats.Repetitions
+	// The answer can't be checked if the stored metadata is invalid.
+	err = f.Metadata.validate()
+	if err != nil {
+		return nil, fmt.Errorf("flashcard %d for session %s: %w", flashcardID, sessionID, err)
+	}
 
-	ok := f.Submit(submission, session.Round)
-	if !ok {
-		return session, ok, nil
+	if submission.selfGraded && session.ReviewMode == ReviewModeReveal && !f.Stats.Revealed {
+		return nil, fmt.Errorf("flashcard %d for session %s: %w", flashcardID, sessionID, ErrNotRevealed)
+	}
+
+	if submission.selfGraded && session.ReviewMode == ReviewModeMultipleChoice {
+		return nil, fmt.Errorf("flashcard %d for session %s: %w", flashcardID, sessionID, ErrNotGradable)
+	}
+
+	if !submission.selfGraded && f.isMultipleChoice(session) && !slices.Contains(f.Stats.Choices, submission.Answer) {
+		return nil, fmt.Errorf("choice %s for flashcard %d: %w", submission.Answer, flashcardID, ErrInvalidChoice)
+	}
+
+	previousStats := f.Stats
+
+	submission.Time = r.now()
+
+	match := f.Submit(submission, session, scheduler)
+
+	err = r.store.AddReviewLogEntry(ctx, sessionID, newReviewLogEntry(f, submission, session, &previousStats, match))
+	if err != nil {
+		return nil, err
 	}
 
 	err = r.store.SetFlashcardStats(ctx, sessionID, f.Metadata.ID, &f.Stats)
 	if err != nil {
-		return nil, false, err
+		return nil, err
+	}
+
+	result := &SubmitResult{Session: session, Stats: f.Stats, IsCorrect: match.IsCorrect(), Match: match}
+
+	if !result.IsCorrect {
+		return result, nil
 	}
 
 	session.IncrementProficiency(f.Stats.Repetitions, 1)
+	session.resetCounts(session.period(submission.Time))
 
-	if previousViewCount != 0 {
-		session.IncrementProficiency(previousRepetitions, -1)
+	if previousStats.ViewCount != 0 {
+		session.IncrementProficiency(previousStats.Repetitions, -1)
+		session.ReviewCount++
 	} else {
 		session.UnreviewedCount--
+		session.NewCount++
 	}
 
 	if session.IsNewRound {
@@ -172,13 +558,222 @@ func (r *Reviewer) Submit(ctx context.Context, sessionID string, flashcardID int
 
 	err = r.store.SetSession(ctx, sessionID, session)
 	if err != nil {
-		return nil, false, err
+		return nil, err
 	}
 
-	return session, ok, nil
+	return result, nil
 }
 
-func getFlashcardMetadata(ctx context.Context, source FlashcardMetadataSource) ([]*FlashcardMetadata, error) {
+// Undo reverts the last correct submission, restoring the flashcard's previous
+// stats and the session's round and counts, and returns the flashcard so that it
+// can be reviewed again. Any incorrect submissions since then are discarded as
+// well. The submissions are only removed from the review log once everything
+// else has been restored, so if anything fails, the undo can simply be retried.
+func (r *Reviewer) Undo(ctx context.Context, sessionID string) (*Flashcard, error) {
+	session, err := r.store.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := r.store.GetReviewLogSinceLastCorrect(ctx, sessionID)
+	if errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("session %s: %w", sessionID, ErrNothingToUndo)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	seqs := make([]int64, 0, len(entries))
+	for _, e := range entries {
+		seqs = append(seqs, e.Seq)
+	}
+
+	entry := entries[len(entries)-1]
+
+	// If the flashcard has been removed since, there's nothing left to restore,
+	// and its submissions no longer count towards the session's counts.
+	_, err = r.store.GetFlashcard(ctx, sessionID, entry.FlashcardID)
+	if errors.Is(err, ErrNotFound) {
+		err = r.store.DeleteReviewLogEntries(ctx, sessionID, seqs)
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("flashcard %d for session %s: %w", entry.FlashcardID, sessionID, ErrNothingToUndo)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Since the entries are restored from newest to oldest, each flashcard ends
+	// up with the stats from before its oldest discarded submission. Whether
+	// it's suspended or buried isn't part of the submission, so it's kept.
+	for _, e := range entries {
+		err = r.restoreStats(ctx, sessionID, e)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	session.IncrementProficiency(entry.Stats.Repetitions, -1)
+
+	if entry.PreviousStats.ViewCount != 0 {
+		session.IncrementProficiency(entry.PreviousStats.Repetitions, 1)
+	} else {
+		session.UnreviewedCount++
+	}
+
+	session.Round = entry.Round
+	session.IsNewRound = entry.IsNewRound
+	session.CountedPeriod = entry.CountedPeriod
+	session.NewCount = entry.NewCount
+	session.ReviewCount = entry.ReviewCount
+
+	err = r.store.SetSession(ctx, sessionID, session)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.store.DeleteReviewLogEntries(ctx, sessionID, seqs)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.visibleFlashcard(ctx, session, entry.FlashcardID)
+}
+
+// restoreStats restores a flashcard's stats from before the submission
+// recorded in the review log entry, unless the flashcard has been removed
+// since. The flashcard stays suspended or buried if it is now.
+func (r *Reviewer) restoreStats(ctx context.Context, sessionID string, entry *ReviewLogEntry) error {
+	f, err := r.store.GetFlashcard(ctx, sessionID, entry.FlashcardID)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	stats := entry.PreviousStats
+	stats.Suspended = f.Stats.Suspended
+	stats.BuriedUntil = f.Stats.BuriedUntil
+
+	return r.store.SetFlashcardStats(ctx, sessionID, entry.FlashcardID, &stats)
+}
+
+// GetReviewLog returns every submission for the session, from oldest to newest.
+func (r *Reviewer) GetReviewLog(ctx context.Context, sessionID string) ([]*ReviewLogEntry, error) {
+	return r.store.GetReviewLog(ctx, sessionID)
+}
+
+// OptimizeFSRS fits the FSRS model weights to the session's review log and
+// stores them on the session, so that they're used for future reviews.
+func (r *Reviewer) OptimizeFSRS(ctx context.Context, sessionID string) (*Session, error) {
+	session, err := r.store.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	log, err := r.store.GetReviewLog(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	weights, err := OptimizeFSRS(log, session.TimeBased, session.FSRSWeights)
+	if err != nil {
+		return nil, err
+	}
+
+	session.FSRSWeights = weights
+
+	err = r.store.SetSession(ctx, sessionID, session)
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// scheduler returns the scheduler used by the specified session. Sessions that
+// predate the introduction of schedulers use the DoublingScheduler.
+func (r *Reviewer) scheduler(session *Session) (Scheduler, error) {
+	name := session.Scheduler
+	if name == "" {
+		name = DoublingSchedulerName
+	}
+
+	scheduler, ok := r.schedulers[name]
+	if !ok {
+		return nil, fmt.Errorf("scheduler %s for session %s: %w", name, session.ID, ErrUnknownScheduler)
+	}
+
+	return scheduler, nil
+}
+
+func validateOptions(options *SessionOptions) error {
+	if options.TargetRetention < 0 || options.TargetRetention >= 1 {
+		return fmt.Errorf("target retention %v: %w", options.TargetRetention, ErrInvalidOptions)
+	}
+
+	if len(options.FSRSWeights) > 0 && !isValidFSRSWeights(options.FSRSWeights) {
+		return fmt.Errorf("FSRS weights %v: %w", options.FSRSWeights, ErrInvalidOptions)
+	}
+
+	if options.IntervalFuzz < 0 || options.IntervalFuzz >= 1 {
+		return fmt.Errorf("interval fuzz %v: %w", options.IntervalFuzz, ErrInvalidOptions)
+	}
+
+	if options.TypoTolerance < 0 || options.TypoTolerance >= 1 {
+		return fmt.Errorf("typo tolerance %v: %w", options.TypoTolerance, ErrInvalidOptions)
+	}
+
+	if !isValidOrdering(options.Ordering) {
+		return fmt.Errorf("ordering %s: %w", options.Ordering, ErrInvalidOptions)
+	}
+
+	if options.LeechThreshold < 0 {
+		return fmt.Errorf("leech threshold %d: %w", options.LeechThreshold, ErrInvalidOptions)
+	}
+
+	switch options.ReviewMode {
+	case "", ReviewModeTyped, ReviewModeReveal, ReviewModeMultipleChoice:
+	default:
+		return fmt.Errorf("review mode %s: %w", options.ReviewMode, ErrInvalidOptions)
+	}
+
+	if options.NumChoices < 0 || options.NumChoices == 1 {
+		return fmt.Errorf("number of choices %d: %w", options.NumChoices, ErrInvalidOptions)
+	}
+
+	for _, cadence := range options.BoxCadences {
+		if cadence <= 0 {
+			return fmt.Errorf("box cadences %v: %w", options.BoxCadences, ErrInvalidOptions)
+		}
+	}
+
+	if options.NewCardsPerRound < 0 || options.MaxReviewsPerRound < 0 {
+		return fmt.Errorf("limits %d and %d: %w", options.NewCardsPerRound, options.MaxReviewsPerRound, ErrInvalidOptions)
+	}
+
+	for _, name := range options.Normalizers {
+		if _, ok := normalizer(name); !ok {
+			return fmt.Errorf("normalizer %s: %w", name, ErrInvalidOptions)
+		}
+	}
+
+	for _, step := range options.RelearningSteps {
+		if step < 0 {
+			return fmt.Errorf("relearning steps %v: %w", options.RelearningSteps, ErrInvalidOptions)
+		}
+	}
+
+	return nil
+}
+
+func getFlashcardMetadata(
+	ctx context.Context,
+	source FlashcardMetadataSource,
+	options *SessionOptions,
+) ([]*FlashcardMetadata, error) {
 	// We intentionally don't preallocate the slice, because we don't know how
 	// many flashcards will be filtered out.
 	var filteredMetadata []*FlashcardMetadata //nolint:prealloc
@@ -194,11 +789,17 @@ func getFlashcardMetadata(ctx context.Context, source FlashcardMetadataSource) (
 		if m.Prompt == "" {
 			continue
 		}
+		err = m.validate()
+		if err != nil {
+			return nil, fmt.Errorf("flashcard %d: %w", m.ID, err)
+		}
 		e, ok := metadataByQualifiedPrompt[m.qualifiedPrompt()]
-		if ok && e.Answer != m.Answer {
+		isAmbiguous := ok && !e.hasSameAnswers(m)
+		// Numeric answers can't be merged, since the parts are compared as text.
+		if isAmbiguous && (!options.MergeAmbiguousAnswers || e.isNumeric() || m.isNumeric()) {
 			return nil, fmt.Errorf("answers %s and %s for prompt %s: %w",
-				e.Answer,
-				m.Answer,
+				e.describeAnswers(),
+				m.describeAnswers(),
 				m.Prompt,
 				ErrAmbiguousAnswers,
 			)
@@ -207,9 +808,74 @@ func getFlashcardMetadata(ctx context.Context, source FlashcardMetadataSource) (
 		filteredMetadata = append(filteredMetadata, m)
 	}
 
+	if options.MergeAmbiguousAnswers {
+		return mergeAmbiguousAnswers(filteredMetadata), nil
+	}
+
 	return filteredMetadata, nil
 }
 
+// mergeAmbiguousAnswers merges flashcards that have the same prompt and context,
+// but different answers, into a single flashcard that requires all of the
+// answers. The merged flashcard keeps the ID of the first of these flashcards,
+// and so does a flashcard whose rows are all duplicates of each other. The
+// answers are sorted, so that reordering the rows doesn't change the flashcard.
+func mergeAmbiguousAnswers(metadata []*FlashcardMetadata) []*FlashcardMetadata {
+	partsByQualifiedPrompt := make(map[qualifiedPrompt][]AnswerPart)
+
+	for _, m := range metadata {
+		part := AnswerPart{Answer: m.Answer, AlternativeAnswers: m.AlternativeAnswers, Pattern: m.Pattern}
+		parts := partsByQualifiedPrompt[m.qualifiedPrompt()]
+		isDuplicate := slices.ContainsFunc(parts, func(p AnswerPart) bool {
+			return slices.Equal(p.acceptedAnswers(), part.acceptedAnswers()) && p.Pattern == part.Pattern
+		})
+		if !isDuplicate {
+			partsByQualifiedPrompt[m.qualifiedPrompt()] = append(parts, part)
+		}
+	}
+
+	// We intentionally don't preallocate the slice, because we don't know how
+	// many flashcards will be merged.
+	var mergedMetadata []*FlashcardMetadata //nolint:prealloc
+
+	for _, m := range metadata {
+		parts, ok := partsByQualifiedPrompt[m.qualifiedPrompt()]
+		if !ok {
+			continue
+		}
+
+		// Only the first flashcard with this prompt is kept.
+		delete(partsByQualifiedPrompt, m.qualifiedPrompt())
+
+		if len(parts) == 1 {
+			mergedMetadata = append(mergedMetadata, m)
+		} else {
+			slices.SortFunc(parts, compareAnswerParts)
+			answers := make([]string, 0, len(parts))
+			for _, p := range parts {
+				answers = append(answers, p.Answer)
+			}
+			mergedMetadata = append(mergedMetadata, &FlashcardMetadata{
+				ID:      m.ID,
+				Prompt:  m.Prompt,
+				Context: m.Context,
+				Answer:  strings.Join(answers, ", "),
+				Parts:   parts,
+			})
+		}
+	}
+
+	return mergedMetadata
+}
+
+// compareAnswerParts orders answer parts by their accepted answers and pattern.
+func compareAnswerParts(a, b AnswerPart) int {
+	return cmp.Or(
+		slices.Compare(a.acceptedAnswers(), b.acceptedAnswers()),
+		cmp.Compare(a.Pattern, b.Pattern),
+	)
+}
+
 func diff(
 	session *Session,
 	flashcards []*Flashcard,
@@ -223,6 +889,10 @@ func diff(
 	updatedSession = NewSession(session.ID, len(session.ProficiencyCounts))
 	updatedSession.Round = session.Round
 	updatedSession.IsNewRound = session.IsNewRound
+	updatedSession.NewCount = session.NewCount
+	updatedSession.ReviewCount = session.ReviewCount
+	updatedSession.CountedPeriod = session.CountedPeriod
+	updatedSession.SessionOptions = session.SessionOptions
 
 	// Update and clean up existing flashcards.
 	for _, f := range flashcards {
@@ -234,7 +904,7 @@ func diff(
 		}
 
 		switch {
-		case f.Metadata != *m:
+		case !f.Metadata.equal(m):
 			fmt.Printf("INFO\tUpdating metadata for ID %d: %v > %v\n", m.ID, f.Metadata, m)
 			toBeUpserted = append(toBeUpserted, m)
 			updatedSession.UnreviewedCount++
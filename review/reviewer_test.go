@@ -2,7 +2,11 @@ package review
 
 import (
 	"context"
+	"math"
+	"slices"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -15,6 +19,7 @@ func TestReviewer_NextFlashcard(t *testing.T) {
 		IsNewRound:        true,
 		ProficiencyCounts: make([]int, numProficiencyLevels),
 		UnreviewedCount:   numFlashcards,
+		SessionOptions:    SessionOptions{Scheduler: DoublingSchedulerName},
 	}
 
 	testCases := []struct {
@@ -29,7 +34,7 @@ func TestReviewer_NextFlashcard(t *testing.T) {
 			expectedFlashcard: &Flashcard{
 				Metadata: flashcardMetadata(1),
 			},
-			expectedSession: &Session{Round: 0, IsNewRound: false, ProficiencyCounts: []int{0, 1, 0}, UnreviewedCount: 1},
+			expectedSession: &Session{Round: 0, IsNewRound: false, ProficiencyCounts: []int{0, 1, 0}, UnreviewedCount: 1, NewCount: 1},
 		},
 		{
 			correct:      true,
@@ -37,7 +42,7 @@ func TestReviewer_NextFlashcard(t *testing.T) {
 			expectedFlashcard: &Flashcard{
 				Metadata: flashcardMetadata(2),
 			},
-			expectedSession: &Session{Round: 1, IsNewRound: false, ProficiencyCounts: []int{0, 2, 0}, UnreviewedCount: 0},
+			expectedSession: &Session{Round: 1, IsNewRound: false, ProficiencyCounts: []int{0, 2, 0}, UnreviewedCount: 0, NewCount: 1, CountedPeriod: 1},
 		},
 		{
 			correct:      true,
@@ -46,7 +51,7 @@ func TestReviewer_NextFlashcard(t *testing.T) {
 				Metadata: flashcardMetadata(1),
 				Stats:    FlashcardStats{ViewCount: 1, Repetitions: 1, NextReview: 1},
 			},
-			expectedSession: &Session{Round: 1, IsNewRound: false, ProficiencyCounts: []int{0, 1, 1}, UnreviewedCount: 0},
+			expectedSession: &Session{Round: 1, IsNewRound: false, ProficiencyCounts: []int{0, 1, 1}, UnreviewedCount: 0, NewCount: 1, ReviewCount: 1, CountedPeriod: 1},
 		},
 		{
 			correct:      true,
@@ -55,7 +60,7 @@ func TestReviewer_NextFlashcard(t *testing.T) {
 				Metadata: flashcardMetadata(2),
 				Stats:    FlashcardStats{ViewCount: 1, Repetitions: 1, NextReview: 2},
 			},
-			expectedSession: &Session{Round: 2, IsNewRound: false, ProficiencyCounts: []int{0, 0, 2}, UnreviewedCount: 0},
+			expectedSession: &Session{Round: 2, IsNewRound: false, ProficiencyCounts: []int{0, 0, 2}, UnreviewedCount: 0, ReviewCount: 1, CountedPeriod: 2},
 		},
 		{
 			correct:      true,
@@ -64,7 +69,7 @@ func TestReviewer_NextFlashcard(t *testing.T) {
 				Metadata: flashcardMetadata(1),
 				Stats:    FlashcardStats{ViewCount: 2, Repetitions: 2, NextReview: 3},
 			},
-			expectedSession: &Session{Round: 3, IsNewRound: false, ProficiencyCounts: []int{0, 0, 2}, UnreviewedCount: 0},
+			expectedSession: &Session{Round: 3, IsNewRound: false, ProficiencyCounts: []int{0, 0, 2}, UnreviewedCount: 0, ReviewCount: 1, CountedPeriod: 3},
 		},
 		{
 			correct:      false,
@@ -73,30 +78,31 @@ func TestReviewer_NextFlashcard(t *testing.T) {
 				Metadata: flashcardMetadata(2),
 				Stats:    FlashcardStats{ViewCount: 2, Repetitions: 2, NextReview: 4},
 			},
-			expectedSession: &Session{Round: 4, IsNewRound: true, ProficiencyCounts: []int{0, 0, 2}, UnreviewedCount: 0},
+			expectedSession: &Session{Round: 4, IsNewRound: true, ProficiencyCounts: []int{0, 0, 2}, UnreviewedCount: 0, CountedPeriod: 4},
 		},
 		{
 			correct:      true,
 			isFirstGuess: false,
 			expectedFlashcard: &Flashcard{
 				Metadata: flashcardMetadata(2),
-				Stats:    FlashcardStats{ViewCount: 2, Repetitions: 2, NextReview: 4},
+				Stats:    FlashcardStats{ViewCount: 2, Repetitions: 2, NextReview: 4, IncorrectCount: 1, IncorrectAnswers: []string{""}},
 			},
-			expectedSession: &Session{Round: 4, IsNewRound: false, ProficiencyCounts: []int{1, 0, 1}, UnreviewedCount: 0},
+			expectedSession: &Session{Round: 4, IsNewRound: false, ProficiencyCounts: []int{1, 0, 1}, UnreviewedCount: 0, ReviewCount: 1, CountedPeriod: 4},
 		},
 	}
 
 	ctx := context.Background()
 
-	r := NewReviewer(NewMemoryStore())
+	r := NewReviewer(NewMemoryStore(), NewDoublingScheduler())
 
-	session, err := r.CreateSession(ctx, newMemorySource(numFlashcards), numProficiencyLevels)
+	session, err := r.CreateSession(ctx, newMemorySource(numFlashcards), numProficiencyLevels, &SessionOptions{})
 	require.NoError(t, err)
 	expectedInitialSession.ID = session.ID
 	require.Equal(t, expectedInitialSession, session)
 
 	for i, tc := range testCases {
 		tc.expectedSession.ID = expectedInitialSession.ID
+		tc.expectedSession.Scheduler = expectedInitialSession.Scheduler
 
 		f, err := r.NextFlashcard(ctx, session.ID)
 		require.NoError(t, err, i)
@@ -109,13 +115,74 @@ func TestReviewer_NextFlashcard(t *testing.T) {
 
 		submission := &Submission{Answer: answer, IsFirstGuess: tc.isFirstGuess}
 
-		session, ok, err := r.Submit(ctx, session.ID, f.Metadata.ID, submission)
+		result, err := r.Submit(ctx, session.ID, f.Metadata.ID, submission)
 		require.NoError(t, err, i)
-		require.Equal(t, tc.correct, ok, i)
-		require.Equal(t, tc.expectedSession, session, i)
+		require.Equal(t, tc.correct, result.IsCorrect, i)
+		require.Equal(t, tc.expectedSession, result.Session, i)
 	}
 }
 
+func TestReviewer_NextFlashcard_timeBased(t *testing.T) {
+	ctx := context.Background()
+
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	r := NewReviewer(NewMemoryStore(), NewDoublingScheduler())
+	r.SetClock(func() time.Time { return now })
+
+	session, err := r.CreateSession(ctx, newMemorySource(1), 3, &SessionOptions{TimeBased: true})
+	require.NoError(t, err)
+
+	f, err := r.NextFlashcard(ctx, session.ID)
+	require.NoError(t, err)
+	require.Equal(t, &Flashcard{Metadata: flashcardMetadata(1)}, f)
+
+	result, err := r.Submit(ctx, session.ID, f.Metadata.ID, &Submission{Answer: "1", IsFirstGuess: true})
+	require.NoError(t, err)
+	require.True(t, result.IsCorrect)
+
+	_, err = r.NextFlashcard(ctx, session.ID)
+	require.ErrorIs(t, err, ErrNothingDue)
+
+	now = now.Add(day)
+
+	f, err = r.NextFlashcard(ctx, session.ID)
+	require.NoError(t, err)
+	require.Equal(t, FlashcardStats{ViewCount: 1, Repetitions: 1, DueAt: now}, f.Stats)
+
+	result, err = r.Submit(ctx, session.ID, f.Metadata.ID, &Submission{Answer: "1", IsFirstGuess: true})
+	require.NoError(t, err)
+	require.True(t, result.IsCorrect)
+
+	f, err = r.store.GetFlashcard(ctx, session.ID, 1)
+	require.NoError(t, err)
+	require.Equal(t, FlashcardStats{ViewCount: 2, Repetitions: 2, DueAt: now.Add(2 * day)}, f.Stats)
+}
+
+func TestReviewer_Grade(t *testing.T) {
+	ctx := context.Background()
+
+	r := NewReviewer(NewMemoryStore(), NewDoublingScheduler())
+
+	session, err := r.CreateSession(ctx, newMemorySource(2), 3, &SessionOptions{})
+	require.NoError(t, err)
+
+	_, err = r.Grade(ctx, session.ID, 1, Grade(5))
+	require.ErrorIs(t, err, ErrInvalidGrade)
+
+	_, err = r.Submit(ctx, session.ID, 1, &Submission{Answer: "1", IsFirstGuess: true, Grade: Grade(-1)})
+	require.ErrorIs(t, err, ErrInvalidGrade)
+
+	session, err = r.Grade(ctx, session.ID, 1, GradeEasy)
+	require.NoError(t, err)
+	require.Equal(t, []int{0, 0, 1}, session.ProficiencyCounts)
+	require.Equal(t, 1, session.UnreviewedCount)
+
+	f, err := r.store.GetFlashcard(ctx, session.ID, 1)
+	require.NoError(t, err)
+	require.Equal(t, FlashcardStats{ViewCount: 1, Repetitions: 2, NextReview: 2}, f.Stats)
+}
+
 func TestReviewer_SyncFlashcards(t *testing.T) {
 	const initialNumFlashcards = 6
 	const numProficiencyLevels = 3
@@ -125,6 +192,7 @@ func TestReviewer_SyncFlashcards(t *testing.T) {
 		IsNewRound:        true,
 		ProficiencyCounts: []int{0, 1, 0},
 		UnreviewedCount:   5,
+		SessionOptions:    SessionOptions{Scheduler: DoublingSchedulerName},
 	}
 
 	metadataUpdate := []*FlashcardMetadata{
@@ -147,9 +215,9 @@ func TestReviewer_SyncFlashcards(t *testing.T) {
 
 	ctx := context.Background()
 
-	r := NewReviewer(NewMemoryStore())
+	r := NewReviewer(NewMemoryStore(), NewDoublingScheduler())
 
-	session, err := r.CreateSession(ctx, newMemorySource(initialNumFlashcards), numProficiencyLevels)
+	session, err := r.CreateSession(ctx, newMemorySource(initialNumFlashcards), numProficiencyLevels, &SessionOptions{})
 	require.NoError(t, err)
 	expectedSession.ID = session.ID
 
@@ -165,6 +233,7 @@ func TestReviewer_SyncFlashcards(t *testing.T) {
 		IsNewRound:        true,
 		ProficiencyCounts: []int{0, 1, 4},
 		UnreviewedCount:   1,
+		SessionOptions:    SessionOptions{Scheduler: DoublingSchedulerName},
 	})
 	require.NoError(t, err)
 
@@ -185,10 +254,834 @@ func TestReviewer_SyncFlashcards(t *testing.T) {
 	require.Equal(t, []*Session{unchangedSession}, sessions)
 }
 
+func TestReviewer_Submit_unknownScheduler(t *testing.T) {
+	ctx := context.Background()
+
+	r := NewReviewer(NewMemoryStore(), NewDoublingScheduler())
+
+	session, err := r.CreateSession(ctx, newMemorySource(1), 3, &SessionOptions{})
+	require.NoError(t, err)
+
+	session.Scheduler = "unknown"
+	err = r.store.SetSession(ctx, session.ID, session)
+	require.NoError(t, err)
+
+	_, err = r.Submit(ctx, session.ID, 1, &Submission{Answer: "1", IsFirstGuess: true})
+	require.ErrorIs(t, err, ErrUnknownScheduler)
+}
+
+func TestReviewer_Submit_invalidPattern(t *testing.T) {
+	ctx := context.Background()
+
+	r := NewReviewer(NewMemoryStore(), NewDoublingScheduler())
+
+	session, err := r.CreateSession(ctx, newMemorySource(1), 3, &SessionOptions{})
+	require.NoError(t, err)
+
+	err = r.store.SetFlashcards(ctx, session.ID, []*FlashcardMetadata{{ID: 1, Prompt: "1", Answer: "1", Pattern: "(1"}})
+	require.NoError(t, err)
+
+	_, err = r.Submit(ctx, session.ID, 1, &Submission{Answer: "1", IsFirstGuess: true})
+	require.ErrorIs(t, err, ErrInvalidPattern)
+}
+
+func TestReviewer_Submit_warning(t *testing.T) {
+	testCases := []struct {
+		id                  string
+		options             *SessionOptions
+		expectedRepetitions int
+	}{
+		{
+			id:                  "Warnings don't count as first guesses",
+			options:             &SessionOptions{IgnoreAccents: true},
+			expectedRepetitions: 0,
+		},
+		{
+			id:                  "Warnings count as first guesses",
+			options:             &SessionOptions{IgnoreAccents: true, WarningsCountAsFirstGuess: true},
+			expectedRepetitions: 1,
+		},
+	}
+
+	ctx := context.Background()
+
+	source := NewMemorySource([]*FlashcardMetadata{{ID: 1, Prompt: "Coffee", Answer: "café"}})
+
+	for _, tc := range testCases {
+		r := NewReviewer(NewMemoryStore(), NewDoublingScheduler())
+
+		session, err := r.CreateSession(ctx, source, 3, tc.options)
+		require.NoError(t, err, tc.id)
+
+		result, err := r.Submit(ctx, session.ID, 1, &Submission{Answer: "cafe", IsFirstGuess: true})
+		require.NoError(t, err, tc.id)
+		require.True(t, result.IsCorrect, tc.id)
+		require.Equal(t, OutcomeWarning, result.Outcome, tc.id)
+		require.Equal(t, []Difference{{Expected: "é", Submitted: "e"}}, result.Differences, tc.id)
+		require.Equal(t, tc.expectedRepetitions, result.Stats.Repetitions, tc.id)
+
+		entries, err := r.GetReviewLog(ctx, session.ID)
+		require.NoError(t, err, tc.id)
+		require.Equal(t, tc.options.WarningsCountAsFirstGuess, entries[0].IsFirstGuess, tc.id)
+	}
+}
+
+func TestReviewer_Submit_merged(t *testing.T) {
+	ctx := context.Background()
+
+	source := NewMemorySource([]*FlashcardMetadata{
+		{ID: 1, Prompt: "Synonyms of big", Answer: "large"},
+		{ID: 2, Prompt: "Synonyms of big", Answer: "huge"},
+	})
+
+	r := NewReviewer(NewMemoryStore(), NewDoublingScheduler())
+
+	session, err := r.CreateSession(ctx, source, 3, &SessionOptions{MergeAmbiguousAnswers: true})
+	require.NoError(t, err)
+	require.Equal(t, 1, session.UnreviewedCount)
+
+	result, err := r.Submit(ctx, session.ID, 1, &Submission{Answer: "huge", IsFirstGuess: true})
+	require.NoError(t, err)
+	require.False(t, result.IsCorrect)
+	require.Equal(t, OutcomePartial, result.Outcome)
+	require.Equal(t, 1, result.Remaining)
+	require.Equal(t, FlashcardStats{NamedParts: []int{0}}, result.Stats)
+	require.Equal(t, 1, result.Session.UnreviewedCount)
+
+	result, err = r.Submit(ctx, session.ID, 1, &Submission{Answer: "huge", IsFirstGuess: true})
+	require.NoError(t, err)
+	require.False(t, result.IsCorrect)
+	require.Equal(t, OutcomeRepeated, result.Outcome)
+	require.Equal(t, 1, result.Remaining)
+	require.Equal(t, FlashcardStats{NamedParts: []int{0}}, result.Stats)
+
+	result, err = r.Submit(ctx, session.ID, 1, &Submission{Answer: "tiny", IsFirstGuess: true})
+	require.NoError(t, err)
+	require.Equal(t, OutcomeWrong, result.Outcome)
+
+	result, err = r.Submit(ctx, session.ID, 1, &Submission{Answer: "large", IsFirstGuess: false})
+	require.NoError(t, err)
+	require.True(t, result.IsCorrect)
+	require.Equal(t, OutcomeCorrect, result.Outcome)
+	require.Equal(t, FlashcardStats{ViewCount: 1, NextReview: 1, IncorrectCount: 1}, result.Stats)
+	require.Equal(t, 0, result.Session.UnreviewedCount)
+}
+
+func TestReviewer_NextFlashcard_multipleChoice(t *testing.T) {
+	ctx := context.Background()
+
+	r := NewReviewer(NewMemoryStore(), NewDoublingScheduler())
+
+	options := &SessionOptions{ReviewMode: ReviewModeMultipleChoice, NumChoices: 3, Seed: 1}
+
+	session, err := r.CreateSession(ctx, newMemorySource(5), 3, options)
+	require.NoError(t, err)
+
+	f, err := r.NextFlashcard(ctx, session.ID)
+	require.NoError(t, err)
+	require.Equal(t, "", f.Metadata.Answer)
+	require.Len(t, f.Stats.Choices, 3)
+	require.Contains(t, f.Stats.Choices, "1")
+
+	again, err := r.NextFlashcard(ctx, session.ID)
+	require.NoError(t, err)
+	require.Equal(t, f.Stats.Choices, again.Stats.Choices)
+
+	flashcards, err := r.GetFlashcards(ctx, session.ID)
+	require.NoError(t, err)
+	for _, f := range flashcards {
+		require.Empty(t, f.Metadata.Answer, f.Metadata.ID)
+	}
+
+	_, err = r.Submit(ctx, session.ID, 1, &Submission{Answer: "one", IsFirstGuess: true})
+	require.ErrorIs(t, err, ErrInvalidChoice)
+
+	_, err = r.Grade(ctx, session.ID, 1, GradeGood)
+	require.ErrorIs(t, err, ErrNotGradable)
+
+	wrong := slices.DeleteFunc(slices.Clone(f.Stats.Choices), func(c string) bool { return c == "1" })[0]
+
+	result, err := r.Submit(ctx, session.ID, 1, &Submission{Answer: wrong, IsFirstGuess: true})
+	require.NoError(t, err)
+	require.Equal(t, OutcomeWrong, result.Outcome)
+	require.Equal(t, "1", result.Answer)
+	require.Equal(t, f.Stats.Choices, result.Stats.Choices)
+
+	result, err = r.Submit(ctx, session.ID, 1, &Submission{Answer: "1", IsFirstGuess: false})
+	require.NoError(t, err)
+	require.Equal(t, OutcomeCorrect, result.Outcome)
+	require.Nil(t, result.Stats.Choices)
+}
+
+func TestReviewer_NextFlashcard_multipleChoicePattern(t *testing.T) {
+	ctx := context.Background()
+
+	r := NewReviewer(NewMemoryStore(), NewDoublingScheduler())
+
+	options := &SessionOptions{ReviewMode: ReviewModeMultipleChoice, NumChoices: 3, Seed: 1}
+
+	source := NewMemorySource([]*FlashcardMetadata{
+		{ID: 1, Prompt: "Capital", Answer: "(the )?capital", Pattern: "(the )?capital"},
+		{ID: 2, Prompt: "City", Answer: "city"},
+	})
+
+	session, err := r.CreateSession(ctx, source, 3, options)
+	require.NoError(t, err)
+
+	// The pattern isn't offered as a choice, so the answer is typed instead.
+	f, err := r.NextFlashcard(ctx, session.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), f.Metadata.ID)
+	require.Empty(t, f.Stats.Choices)
+
+	result, err := r.Submit(ctx, session.ID, 1, &Submission{Answer: "the capital", IsFirstGuess: true})
+	require.NoError(t, err)
+	require.Equal(t, OutcomeCorrect, result.Outcome)
+
+	f, err = r.NextFlashcard(ctx, session.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), f.Metadata.ID)
+	require.Equal(t, []string{"city"}, f.Stats.Choices)
+}
+
+func TestReviewer_Reveal(t *testing.T) {
+	ctx := context.Background()
+
+	r := NewReviewer(NewMemoryStore(), NewDoublingScheduler())
+
+	session, err := r.CreateSession(ctx, newMemorySource(1), 3, &SessionOptions{ReviewMode: ReviewModeReveal})
+	require.NoError(t, err)
+
+	f, err := r.NextFlashcard(ctx, session.ID)
+	require.NoError(t, err)
+	require.Equal(t, &Flashcard{Metadata: FlashcardMetadata{ID: 1, Prompt: "What is 1?"}}, f)
+
+	flashcards, err := r.GetFlashcards(ctx, session.ID)
+	require.NoError(t, err)
+	require.Equal(t, []*Flashcard{f}, flashcards)
+
+	_, err = r.Grade(ctx, session.ID, 1, GradeGood)
+	require.ErrorIs(t, err, ErrNotRevealed)
+
+	f, err = r.Reveal(ctx, session.ID, 1)
+	require.NoError(t, err)
+	require.Equal(t, &Flashcard{Metadata: flashcardMetadata(1), Stats: FlashcardStats{Revealed: true}}, f)
+
+	flashcards, err = r.GetFlashcards(ctx, session.ID)
+	require.NoError(t, err)
+	require.Equal(t, []*Flashcard{f}, flashcards)
+
+	f, err = r.NextFlashcard(ctx, session.ID)
+	require.NoError(t, err)
+	require.Equal(t, flashcardMetadata(1), f.Metadata)
+
+	session, err = r.Grade(ctx, session.ID, 1, GradeGood)
+	require.NoError(t, err)
+	require.Equal(t, []int{0, 1, 0}, session.ProficiencyCounts)
+
+	f, err = r.store.GetFlashcard(ctx, session.ID, 1)
+	require.NoError(t, err)
+	require.Equal(t, FlashcardStats{ViewCount: 1, Repetitions: 1, NextReview: 1}, f.Stats)
+}
+
+func TestReviewer_GetLeeches(t *testing.T) {
+	ctx := context.Background()
+
+	r := NewReviewer(NewMemoryStore(), NewDoublingScheduler())
+
+	options := &SessionOptions{LeechThreshold: 2, SuspendLeeches: true}
+	session, err := r.CreateSession(ctx, newMemorySource(1), 3, options)
+	require.NoError(t, err)
+
+	submissions := []*Submission{
+		{Answer: "1", IsFirstGuess: true},
+		{Answer: "1", IsFirstGuess: false},
+		{Answer: "1", IsFirstGuess: true},
+		{Answer: "1", IsFirstGuess: false},
+	}
+
+	for _, submission := range submissions {
+		f, err := r.NextFlashcard(ctx, session.ID)
+		require.NoError(t, err)
+
+		leeches, err := r.GetLeeches(ctx, session.ID)
+		require.NoError(t, err)
+		require.Empty(t, leeches)
+
+		result, err := r.Submit(ctx, session.ID, f.Metadata.ID, submission)
+		require.NoError(t, err)
+		require.True(t, result.IsCorrect)
+	}
+
+	leeches, err := r.GetLeeches(ctx, session.ID)
+	require.NoError(t, err)
+	require.Len(t, leeches, 1)
+	require.Equal(t, 2, leeches[0].Stats.Lapses)
+	require.True(t, leeches[0].Stats.IsLeech)
+	require.True(t, leeches[0].Stats.Suspended)
+
+	_, err = r.NextFlashcard(ctx, session.ID)
+	require.ErrorIs(t, err, ErrNothingDue)
+}
+
+func TestReviewer_Suspend(t *testing.T) {
+	ctx := context.Background()
+
+	r := NewReviewer(NewMemoryStore(), NewDoublingScheduler())
+
+	session, err := r.CreateSession(ctx, newMemorySource(2), 3, &SessionOptions{})
+	require.NoError(t, err)
+
+	f, err := r.Suspend(ctx, session.ID, 1)
+	require.NoError(t, err)
+	require.True(t, f.Stats.Suspended)
+
+	f, err = r.NextFlashcard(ctx, session.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), f.Metadata.ID)
+
+	_, err = r.Suspend(ctx, session.ID, 2)
+	require.NoError(t, err)
+
+	_, err = r.NextFlashcard(ctx, session.ID)
+	require.ErrorIs(t, err, ErrNothingDue)
+
+	f, err = r.Unsuspend(ctx, session.ID, 1)
+	require.NoError(t, err)
+	require.False(t, f.Stats.Suspended)
+
+	f, err = r.NextFlashcard(ctx, session.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), f.Metadata.ID)
+
+	_, err = r.Suspend(ctx, session.ID, 3)
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestReviewer_Suspend_hiddenAnswer(t *testing.T) {
+	ctx := context.Background()
+
+	for _, mode := range []string{ReviewModeReveal, ReviewModeMultipleChoice} {
+		r := NewReviewer(NewMemoryStore(), NewDoublingScheduler())
+
+		session, err := r.CreateSession(ctx, newMemorySource(2), 3, &SessionOptions{ReviewMode: mode})
+		require.NoError(t, err, mode)
+
+		f, err := r.Suspend(ctx, session.ID, 1)
+		require.NoError(t, err, mode)
+		require.True(t, f.Stats.Suspended, mode)
+		require.Empty(t, f.Metadata.Answer, mode)
+
+		f, err = r.Unsuspend(ctx, session.ID, 1)
+		require.NoError(t, err, mode)
+		require.Empty(t, f.Metadata.Answer, mode)
+
+		f, err = r.Bury(ctx, session.ID, 1)
+		require.NoError(t, err, mode)
+		require.Equal(t, 1, f.Stats.BuriedUntil, mode)
+		require.Empty(t, f.Metadata.Answer, mode)
+	}
+}
+
+func TestReviewer_Bury(t *testing.T) {
+	ctx := context.Background()
+
+	r := NewReviewer(NewMemoryStore(), NewDoublingScheduler())
+
+	session, err := r.CreateSession(ctx, newMemorySource(2), 3, &SessionOptions{})
+	require.NoError(t, err)
+
+	f, err := r.Bury(ctx, session.ID, 1)
+	require.NoError(t, err)
+	require.Equal(t, 1, f.Stats.BuriedUntil)
+
+	f, err = r.NextFlashcard(ctx, session.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), f.Metadata.ID)
+
+	result, err := r.Submit(ctx, session.ID, 2, &Submission{Answer: "2", IsFirstGuess: true})
+	require.NoError(t, err)
+	require.True(t, result.IsCorrect)
+
+	f, err = r.NextFlashcard(ctx, session.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), f.Metadata.ID)
+
+	session, err = r.GetSession(ctx, session.ID)
+	require.NoError(t, err)
+	require.Equal(t, 1, session.Round)
+}
+
+func TestReviewer_NextFlashcard_limits(t *testing.T) {
+	ctx := context.Background()
+
+	r := NewReviewer(NewMemoryStore(), NewDoublingScheduler())
+
+	options := &SessionOptions{NewCardsPerRound: 2, MaxReviewsPerRound: 1}
+	session, err := r.CreateSession(ctx, newMemorySource(3), 3, options)
+	require.NoError(t, err)
+
+	// A flashcard ID of 0 means that the limit on reviews should have been
+	// reached, so the next round has to be started explicitly.
+	expectedReviews := []struct {
+		flashcardID int64
+		round       int
+	}{
+		{flashcardID: 1, round: 0},
+		{flashcardID: 2, round: 0},
+		{flashcardID: 3, round: 1},
+		{flashcardID: 1, round: 1},
+		{flashcardID: 0, round: 1},
+		{flashcardID: 2, round: 2},
+		{flashcardID: 0, round: 2},
+		{flashcardID: 1, round: 3},
+	}
+
+	for i, expected := range expectedReviews {
+		f, err := r.NextFlashcard(ctx, session.ID)
+
+		if expected.flashcardID == 0 {
+			require.ErrorIs(t, err, ErrLimitReached, i)
+
+			current, err := r.GetSession(ctx, session.ID)
+			require.NoError(t, err, i)
+			require.Equal(t, expected.round, current.Round, i)
+
+			current, err = r.NextRound(ctx, session.ID)
+			require.NoError(t, err, i)
+			require.Equal(t, expected.round+1, current.Round, i)
+			require.Zero(t, current.ReviewCount, i)
+			continue
+		}
+
+		require.NoError(t, err, i)
+		require.Equal(t, expected.flashcardID, f.Metadata.ID, i)
+
+		result, err := r.Submit(ctx, session.ID, f.Metadata.ID, &Submission{Answer: f.Metadata.Answer, IsFirstGuess: true})
+		require.NoError(t, err, i)
+		require.True(t, result.IsCorrect, i)
+		require.Equal(t, expected.round, result.Session.Round, i)
+	}
+}
+
+func TestReviewer_NextFlashcard_interleaved(t *testing.T) {
+	ctx := context.Background()
+
+	r := NewReviewer(NewMemoryStore(), NewDoublingScheduler())
+
+	options := &SessionOptions{Ordering: OrderingInterleaved, NewCardsPerRound: 2}
+	session, err := r.CreateSession(ctx, newMemorySource(4), 3, options)
+	require.NoError(t, err)
+
+	expectedIDs := []int64{1, 2, 3, 1, 4, 2}
+
+	for i, expectedID := range expectedIDs {
+		f, err := r.NextFlashcard(ctx, session.ID)
+		require.NoError(t, err, i)
+		require.Equal(t, expectedID, f.Metadata.ID, i)
+
+		result, err := r.Submit(ctx, session.ID, f.Metadata.ID, &Submission{Answer: f.Metadata.Answer, IsFirstGuess: true})
+		require.NoError(t, err, i)
+		require.True(t, result.IsCorrect, i)
+	}
+}
+
+func TestReviewer_CreateSession_seed(t *testing.T) {
+	ctx := context.Background()
+
+	r := NewReviewer(NewMemoryStore(), NewDoublingScheduler())
+
+	session, err := r.CreateSession(ctx, newMemorySource(1), 3, &SessionOptions{Ordering: OrderingRandom})
+	require.NoError(t, err)
+	require.NotZero(t, session.Seed)
+
+	session, err = r.CreateSession(ctx, newMemorySource(1), 3, &SessionOptions{Ordering: OrderingRandom, Seed: 42})
+	require.NoError(t, err)
+	require.Equal(t, int64(42), session.Seed)
+}
+
+func TestReviewer_GetReviewLog(t *testing.T) {
+	ctx := context.Background()
+
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	r := NewReviewer(NewMemoryStore(), NewDoublingScheduler())
+	r.SetClock(func() time.Time { return now })
+
+	session, err := r.CreateSession(ctx, newMemorySource(1), 3, &SessionOptions{})
+	require.NoError(t, err)
+
+	result, err := r.Submit(ctx, session.ID, 1, &Submission{Answer: "2", IsFirstGuess: true})
+	require.NoError(t, err)
+	require.False(t, result.IsCorrect)
+
+	result, err = r.Submit(ctx, session.ID, 1, &Submission{Answer: "1", IsFirstGuess: false})
+	require.NoError(t, err)
+	require.True(t, result.IsCorrect)
+
+	expectedEntries := []*ReviewLogEntry{
+		{
+			Seq:          1,
+			FlashcardID:  1,
+			Answer:       "2",
+			IsFirstGuess: true,
+			IsNewRound:   true,
+			Time:         now,
+			Stats:        FlashcardStats{IncorrectCount: 1},
+		},
+		{
+			Seq:           2,
+			FlashcardID:   1,
+			Answer:        "1",
+			IsCorrect:     true,
+			Grade:         GradeAgain,
+			IsNewRound:    true,
+			Time:          now,
+			PreviousStats: FlashcardStats{IncorrectCount: 1},
+			Stats:         FlashcardStats{ViewCount: 1, NextReview: 1, IncorrectCount: 1},
+		},
+	}
+
+	entries, err := r.GetReviewLog(ctx, session.ID)
+	require.NoError(t, err)
+	require.Equal(t, expectedEntries, entries)
+
+	_, err = r.GetReviewLog(ctx, "unknown")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestReviewer_Undo(t *testing.T) {
+	ctx := context.Background()
+
+	r := NewReviewer(NewMemoryStore(), NewDoublingScheduler())
+
+	session, err := r.CreateSession(ctx, newMemorySource(2), 3, &SessionOptions{})
+	require.NoError(t, err)
+
+	submissions := []struct {
+		flashcardID int64
+		submission  *Submission
+	}{
+		{flashcardID: 1, submission: &Submission{Answer: "1", IsFirstGuess: true}},
+		{flashcardID: 2, submission: &Submission{Answer: "2", IsFirstGuess: true}},
+		{flashcardID: 1, submission: &Submission{Answer: "x", IsFirstGuess: true}},
+		{flashcardID: 1, submission: &Submission{Answer: "1", IsFirstGuess: false}},
+	}
+
+	for i, s := range submissions {
+		_, err = r.NextFlashcard(ctx, session.ID)
+		require.NoError(t, err, i)
+
+		_, err = r.Submit(ctx, session.ID, s.flashcardID, s.submission)
+		require.NoError(t, err, i)
+	}
+
+	session, err = r.GetSession(ctx, session.ID)
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 1, 0}, session.ProficiencyCounts)
+
+	undos := []struct {
+		expectedFlashcard       *Flashcard
+		expectedProficiency     []int
+		expectedUnreviewedCount int
+		expectedRound           int
+		expectedIsNewRound      bool
+		expectedLogLength       int
+	}{
+		{
+			expectedFlashcard: &Flashcard{
+				Metadata: flashcardMetadata(1),
+				Stats:    FlashcardStats{ViewCount: 1, Repetitions: 1, NextReview: 1, IncorrectCount: 1},
+			},
+			expectedProficiency:     []int{0, 2, 0},
+			expectedUnreviewedCount: 0,
+			expectedRound:           1,
+			expectedIsNewRound:      false,
+			expectedLogLength:       3,
+		},
+		{
+			expectedFlashcard:       &Flashcard{Metadata: flashcardMetadata(2)},
+			expectedProficiency:     []int{0, 1, 0},
+			expectedUnreviewedCount: 1,
+			expectedRound:           1,
+			expectedIsNewRound:      true,
+			expectedLogLength:       1,
+		},
+		{
+			expectedFlashcard:       &Flashcard{Metadata: flashcardMetadata(1)},
+			expectedProficiency:     []int{0, 0, 0},
+			expectedUnreviewedCount: 2,
+			expectedRound:           0,
+			expectedIsNewRound:      true,
+			expectedLogLength:       0,
+		},
+	}
+
+	for i, undo := range undos {
+		f, err := r.Undo(ctx, session.ID)
+		require.NoError(t, err, i)
+		require.Equal(t, undo.expectedFlashcard, f, i)
+
+		session, err = r.GetSession(ctx, session.ID)
+		require.NoError(t, err, i)
+		require.Equal(t, undo.expectedProficiency, session.ProficiencyCounts, i)
+		require.Equal(t, undo.expectedUnreviewedCount, session.UnreviewedCount, i)
+		require.Equal(t, undo.expectedRound, session.Round, i)
+		require.Equal(t, undo.expectedIsNewRound, session.IsNewRound, i)
+
+		entries, err := r.GetReviewLog(ctx, session.ID)
+		require.NoError(t, err, i)
+		require.Len(t, entries, undo.expectedLogLength, i)
+	}
+
+	_, err = r.Undo(ctx, session.ID)
+	require.ErrorIs(t, err, ErrNothingToUndo)
+
+	_, err = r.Undo(ctx, "unknown")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	// The answer stays hidden in review modes that hide it.
+	for _, mode := range []string{ReviewModeReveal, ReviewModeMultipleChoice} {
+		session, err = r.CreateSession(ctx, newMemorySource(2), 3, &SessionOptions{ReviewMode: mode})
+		require.NoError(t, err, mode)
+
+		f, err := r.NextFlashcard(ctx, session.ID)
+		require.NoError(t, err, mode)
+
+		if mode == ReviewModeReveal {
+			_, err = r.Reveal(ctx, session.ID, f.Metadata.ID)
+			require.NoError(t, err, mode)
+			_, err = r.Grade(ctx, session.ID, f.Metadata.ID, GradeGood)
+		} else {
+			_, err = r.Submit(ctx, session.ID, f.Metadata.ID, &Submission{Answer: "1", IsFirstGuess: true})
+		}
+		require.NoError(t, err, mode)
+
+		f, err = r.Undo(ctx, session.ID)
+		require.NoError(t, err, mode)
+		require.Equal(t, int64(1), f.Metadata.ID, mode)
+		require.Empty(t, f.Metadata.Answer, mode)
+		require.False(t, f.Stats.Revealed, mode)
+	}
+}
+
+func TestReviewer_Undo_suspended(t *testing.T) {
+	ctx := context.Background()
+
+	r := NewReviewer(NewMemoryStore(), NewDoublingScheduler())
+
+	session, err := r.CreateSession(ctx, newMemorySource(2), 3, &SessionOptions{})
+	require.NoError(t, err)
+
+	_, err = r.Submit(ctx, session.ID, 1, &Submission{Answer: "1", IsFirstGuess: true})
+	require.NoError(t, err)
+
+	_, err = r.Suspend(ctx, session.ID, 1)
+	require.NoError(t, err)
+
+	_, err = r.Bury(ctx, session.ID, 1)
+	require.NoError(t, err)
+
+	f, err := r.Undo(ctx, session.ID)
+	require.NoError(t, err)
+	require.Equal(t, 0, f.Stats.ViewCount)
+	require.True(t, f.Stats.Suspended)
+	require.Equal(t, 1, f.Stats.BuriedUntil)
+}
+
+func TestReviewer_Undo_nextRound(t *testing.T) {
+	ctx := context.Background()
+
+	r := NewReviewer(NewMemoryStore(), NewDoublingScheduler())
+
+	session, err := r.CreateSession(ctx, newMemorySource(3), 3, &SessionOptions{NewCardsPerRound: 2})
+	require.NoError(t, err)
+
+	for id := range int64(2) {
+		_, err = r.Submit(ctx, session.ID, id+1, &Submission{Answer: strconv.FormatInt(id+1, 10), IsFirstGuess: true})
+		require.NoError(t, err, id)
+	}
+
+	session, err = r.NextRound(ctx, session.ID)
+	require.NoError(t, err)
+	require.Equal(t, 1, session.Round)
+
+	_, err = r.Undo(ctx, session.ID)
+	require.NoError(t, err)
+
+	// The undone round's counts are restored, so its limits still apply.
+	session, err = r.GetSession(ctx, session.ID)
+	require.NoError(t, err)
+	require.Equal(t, 0, session.Round)
+	require.Equal(t, 0, session.CountedPeriod)
+	require.Equal(t, 1, session.NewCount)
+	require.Equal(t, 0, session.ReviewCount)
+}
+
+func TestReviewer_Undo_deletedFlashcard(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewMemoryStore()
+	r := NewReviewer(store, NewDoublingScheduler())
+
+	session, err := r.CreateSession(ctx, newMemorySource(2), 3, &SessionOptions{})
+	require.NoError(t, err)
+
+	_, err = r.Submit(ctx, session.ID, 1, &Submission{Answer: "1", IsFirstGuess: true})
+	require.NoError(t, err)
+
+	_, err = r.Submit(ctx, session.ID, 2, &Submission{Answer: "x", IsFirstGuess: true})
+	require.NoError(t, err)
+
+	metadata := flashcardMetadata(2)
+	_, err = r.SyncFlashcards(ctx, session.ID, NewMemorySource([]*FlashcardMetadata{&metadata}))
+	require.NoError(t, err)
+
+	_, err = r.Undo(ctx, session.ID)
+	require.ErrorIs(t, err, ErrNothingToUndo)
+
+	flashcards, err := store.GetFlashcards(ctx, session.ID)
+	require.NoError(t, err)
+	require.Len(t, flashcards, 1)
+
+	entries, err := r.GetReviewLog(ctx, session.ID)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	_, err = store.GetReviewLogSinceLastCorrect(ctx, "unknown")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	err = store.DeleteReviewLogEntries(ctx, "unknown", []int64{1})
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestReviewer_CreateSession(t *testing.T) {
+	testCases := []struct {
+		id                           string
+		options                      *SessionOptions
+		expectedScheduler            string
+		expectedNumProficiencyLevels int
+		expectedErr                  error
+	}{
+		{
+			id:                           "Default scheduler",
+			options:                      &SessionOptions{},
+			expectedScheduler:            DoublingSchedulerName,
+			expectedNumProficiencyLevels: 3,
+		},
+		{
+			id:                           "Alternative scheduler",
+			options:                      &SessionOptions{Scheduler: SM2SchedulerName},
+			expectedScheduler:            SM2SchedulerName,
+			expectedNumProficiencyLevels: 3,
+		},
+		{
+			id:                           "Leitner scheduler with default box cadences",
+			options:                      &SessionOptions{Scheduler: LeitnerSchedulerName},
+			expectedScheduler:            LeitnerSchedulerName,
+			expectedNumProficiencyLevels: len(DefaultBoxCadences),
+		},
+		{
+			id:                           "Leitner scheduler with custom box cadences",
+			options:                      &SessionOptions{Scheduler: LeitnerSchedulerName, BoxCadences: []int{1, 3}},
+			expectedScheduler:            LeitnerSchedulerName,
+			expectedNumProficiencyLevels: 2,
+		},
+		{
+			id:          "Unknown scheduler",
+			options:     &SessionOptions{Scheduler: "unknown"},
+			expectedErr: ErrUnknownScheduler,
+		},
+		{
+			id:          "Invalid box cadences",
+			options:     &SessionOptions{Scheduler: LeitnerSchedulerName, BoxCadences: []int{1, 0}},
+			expectedErr: ErrInvalidOptions,
+		},
+		{
+			id:          "Invalid new card limit",
+			options:     &SessionOptions{NewCardsPerRound: -1},
+			expectedErr: ErrInvalidOptions,
+		},
+		{
+			id:          "Invalid relearning steps",
+			options:     &SessionOptions{RelearningSteps: []int{0, -1}},
+			expectedErr: ErrInvalidOptions,
+		},
+		{
+			id:          "Invalid interval fuzz",
+			options:     &SessionOptions{IntervalFuzz: 1},
+			expectedErr: ErrInvalidOptions,
+		},
+		{
+			id:          "Invalid typo tolerance",
+			options:     &SessionOptions{TypoTolerance: -0.1},
+			expectedErr: ErrInvalidOptions,
+		},
+		{
+			id:          "Invalid ordering",
+			options:     &SessionOptions{Ordering: "unknown"},
+			expectedErr: ErrInvalidOptions,
+		},
+		{
+			id:          "Invalid leech threshold",
+			options:     &SessionOptions{LeechThreshold: -1},
+			expectedErr: ErrInvalidOptions,
+		},
+		{
+			id:          "Invalid normalizer",
+			options:     &SessionOptions{Normalizers: []string{NormalizerTrim, "unknown"}},
+			expectedErr: ErrInvalidOptions,
+		},
+		{
+			id:          "Invalid number of choices",
+			options:     &SessionOptions{ReviewMode: ReviewModeMultipleChoice, NumChoices: 1},
+			expectedErr: ErrInvalidOptions,
+		},
+		{
+			id:          "Invalid review mode",
+			options:     &SessionOptions{ReviewMode: "unknown"},
+			expectedErr: ErrInvalidOptions,
+		},
+		{
+			id:          "Invalid FSRS weights",
+			options:     &SessionOptions{Scheduler: FSRSSchedulerName, FSRSWeights: []float64{1, 2, 3}},
+			expectedErr: ErrInvalidOptions,
+		},
+		{
+			id:          "Invalid target retention",
+			options:     &SessionOptions{Scheduler: FSRSSchedulerName, TargetRetention: 1},
+			expectedErr: ErrInvalidOptions,
+		},
+	}
+
+	ctx := context.Background()
+
+	r := NewReviewer(NewMemoryStore(),
+		NewDoublingScheduler(),
+		NewSM2Scheduler(),
+		NewFSRSScheduler(),
+		NewLeitnerScheduler(),
+	)
+
+	for _, tc := range testCases {
+		t.Run(tc.id, func(t *testing.T) {
+			session, err := r.CreateSession(ctx, newMemorySource(1), 3, tc.options)
+			if tc.expectedErr != nil {
+				require.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedScheduler, session.Scheduler)
+			require.Len(t, session.ProficiencyCounts, tc.expectedNumProficiencyLevels)
+		})
+	}
+}
+
 func TestNewReviewer_getFlashcardMetadata(t *testing.T) {
 	testCases := []struct {
 		id               string
 		metadata         []*FlashcardMetadata
+		options          SessionOptions
 		expectedMetadata []*FlashcardMetadata
 		expectedErr      string
 	}{
@@ -213,13 +1106,141 @@ func TestNewReviewer_getFlashcardMetadata(t *testing.T) {
 			},
 			expectedErr: "answers A1 and A2 for prompt P1: answers are ambiguous",
 		},
+		{
+			id: "Same alternative answers",
+			metadata: []*FlashcardMetadata{
+				{ID: 1, Prompt: "P1", Answer: "A1", AlternativeAnswers: []string{"B1"}},
+				{ID: 2, Prompt: "P1", Answer: "A1", AlternativeAnswers: []string{"B1"}},
+			},
+			expectedMetadata: []*FlashcardMetadata{
+				{ID: 1, Prompt: "P1", Answer: "A1", AlternativeAnswers: []string{"B1"}},
+				{ID: 2, Prompt: "P1", Answer: "A1", AlternativeAnswers: []string{"B1"}},
+			},
+		},
+		{
+			id: "Different alternative answers",
+			metadata: []*FlashcardMetadata{
+				{ID: 1, Prompt: "P1", Answer: "A1", AlternativeAnswers: []string{"B1"}},
+				{ID: 2, Prompt: "P1", Answer: "A1"},
+			},
+			expectedErr: "answers A1 | B1 and A1 for prompt P1: answers are ambiguous",
+		},
+		{
+			id: "Different patterns",
+			metadata: []*FlashcardMetadata{
+				{ID: 1, Prompt: "P1", Answer: "A1", Pattern: "A1|B1"},
+				{ID: 2, Prompt: "P1", Answer: "A1"},
+			},
+			expectedErr: "answers A1 | pattern A1|B1 and A1 for prompt P1: answers are ambiguous",
+		},
+		{
+			id: "Invalid pattern",
+			metadata: []*FlashcardMetadata{
+				{ID: 1, Prompt: "P1", Answer: "A1", Pattern: "(A1"},
+			},
+			expectedErr: "flashcard 1: pattern (A1: pattern is invalid: error parsing regexp: missing closing ): `(A1`",
+		},
+		{
+			id: "Invalid numeric answer",
+			metadata: []*FlashcardMetadata{
+				{ID: 1, Prompt: "P1", Answer: "A1", AnswerType: AnswerTypeNumeric},
+			},
+			expectedErr: "flashcard 1: quantity A1: number is invalid",
+		},
+		{
+			id: "Unknown answer type",
+			metadata: []*FlashcardMetadata{
+				{ID: 1, Prompt: "P1", Answer: "A1", AnswerType: "date"},
+			},
+			expectedErr: "flashcard 1: answer type date: unknown answer type",
+		},
+		{
+			id: "Invalid tolerance",
+			metadata: []*FlashcardMetadata{
+				{ID: 1, Prompt: "P1", Answer: "1", AnswerType: AnswerTypeNumeric, AbsoluteTolerance: math.Inf(1)},
+			},
+			expectedErr: "flashcard 1: tolerances +Inf and 0: number is invalid",
+		},
+		{
+			id: "Different tolerances",
+			metadata: []*FlashcardMetadata{
+				{ID: 1, Prompt: "P1", Answer: "1", AnswerType: AnswerTypeNumeric, AbsoluteTolerance: 0.1},
+				{ID: 2, Prompt: "P1", Answer: "1", AnswerType: AnswerTypeNumeric},
+			},
+			expectedErr: "answers 1 | numeric with tolerances 0.1 and 0 and 1 | numeric with tolerances 0 and 0 " +
+				"for prompt P1: answers are ambiguous",
+		},
+		{
+			id: "Different answer types",
+			metadata: []*FlashcardMetadata{
+				{ID: 1, Prompt: "P1", Answer: "1", AnswerType: AnswerTypeNumeric},
+				{ID: 2, Prompt: "P1", Answer: "1"},
+			},
+			options:     SessionOptions{MergeAmbiguousAnswers: true},
+			expectedErr: "answers 1 | numeric with tolerances 0 and 0 and 1 for prompt P1: answers are ambiguous",
+		},
+		{
+			id: "Same numeric answers",
+			metadata: []*FlashcardMetadata{
+				{ID: 1, Prompt: "P1", Answer: "1", AnswerType: AnswerTypeNumeric, RelativeTolerance: 0.01},
+				{ID: 2, Prompt: "P1", Answer: "1", AnswerType: AnswerTypeNumeric, RelativeTolerance: 0.01},
+			},
+			options: SessionOptions{MergeAmbiguousAnswers: true},
+			expectedMetadata: []*FlashcardMetadata{
+				{ID: 1, Prompt: "P1", Answer: "1", AnswerType: AnswerTypeNumeric, RelativeTolerance: 0.01},
+			},
+		},
+		{
+			id: "Merged",
+			metadata: []*FlashcardMetadata{
+				{ID: 1, Prompt: "P1", Answer: "A1", Context: "C1"},
+				{ID: 2, Prompt: "P2", Answer: "A2"},
+				{ID: 3, Prompt: "P1", Answer: "A3", Context: "C1", AlternativeAnswers: []string{"B3"}},
+				{ID: 4, Prompt: "P1", Answer: "A1", Context: "C1"},
+				{ID: 5, Prompt: "P2", Answer: "A2"},
+			},
+			options: SessionOptions{MergeAmbiguousAnswers: true},
+			expectedMetadata: []*FlashcardMetadata{
+				{
+					ID:      1,
+					Prompt:  "P1",
+					Context: "C1",
+					Answer:  "A1, A3",
+					Parts: []AnswerPart{
+						{Answer: "A1"},
+						{Answer: "A3", AlternativeAnswers: []string{"B3"}},
+					},
+				},
+				{ID: 2, Prompt: "P2", Answer: "A2"},
+			},
+		},
+		{
+			id: "Merged in a different order",
+			metadata: []*FlashcardMetadata{
+				{ID: 3, Prompt: "P1", Answer: "A3", Context: "C1", AlternativeAnswers: []string{"B3"}},
+				{ID: 1, Prompt: "P1", Answer: "A1", Context: "C1"},
+			},
+			options: SessionOptions{MergeAmbiguousAnswers: true},
+			expectedMetadata: []*FlashcardMetadata{
+				{
+					ID:      3,
+					Prompt:  "P1",
+					Context: "C1",
+					Answer:  "A1, A3",
+					Parts: []AnswerPart{
+						{Answer: "A1"},
+						{Answer: "A3", AlternativeAnswers: []string{"B3"}},
+					},
+				},
+			},
+		},
 	}
 
 	ctx := context.Background()
 
 	for _, tc := range testCases {
 		t.Run(tc.id, func(t *testing.T) {
-			metadata, err := getFlashcardMetadata(ctx, NewMemorySource(tc.metadata))
+			metadata, err := getFlashcardMetadata(ctx, NewMemorySource(tc.metadata), &tc.options)
 			if tc.expectedErr != "" {
 				require.EqualError(t, err, tc.expectedErr)
 			} else {
@@ -0,0 +1,89 @@
+package review
+
+import (
+	"context"
+	"time"
+)
+
+// ReviewLogStore records every submission, for analytics and the like.
+type ReviewLogStore interface {
+	// AddReviewLogEntry appends an entry to the review log, assigning it the next sequence number.
+	AddReviewLogEntry(ctx context.Context, sessionID string, entry *ReviewLogEntry) error
+	// GetReviewLog returns all review log entries, from oldest to newest.
+	GetReviewLog(ctx context.Context, sessionID string) ([]*ReviewLogEntry, error)
+	// GetReviewLogSinceLastCorrect returns the newest review log entries, from newest to oldest,
+	// up to and including the newest correct one.
+	GetReviewLogSinceLastCorrect(ctx context.Context, sessionID string) ([]*ReviewLogEntry, error)
+	// DeleteReviewLogEntries deletes the review log entries with the specified sequence numbers.
+	DeleteReviewLogEntries(ctx context.Context, sessionID string, seqs []int64) error
+}
+
+// ReviewLogEntry records a single submission.
+type ReviewLogEntry struct {
+	// Seq orders the entries within the session's review log, starting from 1. It's assigned by the store.
+	Seq int64 `firestore:"seq" json:"seq"`
+	// FlashcardID identifies the reviewed flashcard.
+	FlashcardID int64 `firestore:"flashcardId" json:"flashcardId"`
+	// Answer is the submitted answer (empty for self-graded submissions).
+	Answer string `firestore:"answer" json:"answer"`
+	// IsCorrect is true if and only if the answer was accepted.
+	IsCorrect bool `firestore:"isCorrect" json:"isCorrect"`
+	// IsFirstGuess is true if and only if this was the user's first guess.
+	IsFirstGuess bool `firestore:"isFirstGuess" json:"isFirstGuess"`
+	// Grade rates how well the flashcard was recalled (correct answers only).
+	Grade Grade `firestore:"grade,omitempty" json:"grade,omitempty"`
+	// Round is the round in which the submission was made.
+	Round int `firestore:"round" json:"round"`
+	// IsNewRound is true if and only if the round had just started when the submission was made.
+	IsNewRound bool `firestore:"isNewRound" json:"isNewRound"`
+	// CountedPeriod is the round (or day) in which the submission was made, to which NewCount and ReviewCount refer.
+	CountedPeriod int `firestore:"countedPeriod,omitempty" json:"countedPeriod,omitempty"`
+	// NewCount is the number of new flashcards reviewed in the counted period before the submission.
+	NewCount int `firestore:"newCount,omitempty" json:"newCount,omitempty"`
+	// ReviewCount is the number of previously reviewed flashcards reviewed in the counted period before the submission.
+	ReviewCount int `firestore:"reviewCount,omitempty" json:"reviewCount,omitempty"`
+	// Time is when the submission was made.
+	Time time.Time `firestore:"time" json:"time"`
+	// PreviousStats are the flashcard's stats before the submission, without the state of the review in progress.
+	PreviousStats FlashcardStats `firestore:"previousStats" json:"previousStats"`
+	// Stats are the flashcard's stats after the submission, without the state of the review in progress.
+	Stats FlashcardStats `firestore:"stats" json:"stats"`
+}
+
+// newReviewLogEntry records a submission for the flashcard, whose stats have
+// already been updated.
+func newReviewLogEntry(
+	f *Flashcard,
+	submission *Submission,
+	session *Session,
+	previousStats *FlashcardStats,
+	match *Match,
+) *ReviewLogEntry {
+	submission = submission.counted(previousStats, match, session)
+
+	// The counts are recorded as of the submission's period, so that undoing
+	// the submission restores them even if another period has started since.
+	counts := *session
+	counts.resetCounts(session.period(submission.Time))
+
+	entry := &ReviewLogEntry{
+		FlashcardID:   f.Metadata.ID,
+		Answer:        submission.Answer,
+		IsCorrect:     match.IsCorrect(),
+		IsFirstGuess:  submission.IsFirstGuess,
+		Round:         session.Round,
+		IsNewRound:    session.IsNewRound,
+		CountedPeriod: counts.CountedPeriod,
+		NewCount:      counts.NewCount,
+		ReviewCount:   counts.ReviewCount,
+		Time:          submission.Time,
+		PreviousStats: previousStats.logged(),
+		Stats:         f.Stats.logged(),
+	}
+
+	if match.IsCorrect() {
+		entry.Grade = submission.grade()
+	}
+
+	return entry
+}
@@ -0,0 +1,72 @@
+package review
+
+import (
+	"math"
+	"math/rand/v2"
+)
+
+const (
+	// DoublingSchedulerName identifies the DoublingScheduler.
+	DoublingSchedulerName = "doubling"
+
+	spacedRepetitionFactor = 2
+	// easyIncrement is the number of repetitions by which easy flashcards advance.
+	easyIncrement = 2
+)
+
+// Scheduler decides when a flashcard is due to be reviewed next.
+type Scheduler interface {
+	// Name uniquely identifies the scheduler.
+	Name() string
+	// Schedule returns the updated stats for a flashcard that has just been
+	// answered correctly, along with the number of rounds until its next review
+	// (or days, for time-based sessions).
+	Schedule(session *Session, stats FlashcardStats, submission *Submission) (FlashcardStats, int)
+}
+
+// DoublingScheduler doubles the interval between reviews every time the
+// flashcard is answered correctly on the first guess. Hard flashcards repeat
+// the previous interval instead, while easy flashcards skip a doubling.
+type DoublingScheduler struct{}
+
+// NewDoublingScheduler returns a new DoublingScheduler.
+func NewDoublingScheduler() *DoublingScheduler {
+	return &DoublingScheduler{}
+}
+
+// Name uniquely identifies the scheduler.
+func (s *DoublingScheduler) Name() string {
+	return DoublingSchedulerName
+}
+
+// Schedule returns the updated stats for a flashcard that has just been
+// answered correctly, along with the number of rounds until its next review.
+func (s *DoublingScheduler) Schedule(_ *Session, stats FlashcardStats, submission *Submission) (FlashcardStats, int) {
+	switch submission.grade() {
+	case GradeAgain:
+		stats.Repetitions = 0
+		return stats, 1
+	case GradeHard:
+		return stats, interval(max(stats.Repetitions-1, 0))
+	case GradeEasy:
+		next := interval(stats.Repetitions + easyIncrement - 1)
+		stats.Repetitions += easyIncrement
+		return stats, next
+	default:
+		next := interval(stats.Repetitions)
+		stats.Repetitions++
+		return stats, next
+	}
+}
+
+func interval(repetitions int) int {
+	return int(math.Round(math.Pow(spacedRepetitionFactor, float64(repetitions))))
+}
+
+// fuzzInterval randomly lengthens or shortens the interval by up to the
+// specified fraction, using the key to make the result reproducible.
+func fuzzInterval(interval int, fuzz float64, key uint64) int {
+	u := rand.New(rand.NewPCG(key, 0)).Float64()
+	factor := 1 + fuzz*(2*u-1)
+	return max(1, int(math.Round(float64(interval)*factor)))
+}
@@ -0,0 +1,105 @@
+package review
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoublingScheduler_Schedule(t *testing.T) {
+	testCases := []struct {
+		id                  string
+		repetitions         int
+		submission          *Submission
+		expectedRepetitions int
+		expectedInterval    int
+	}{
+		{
+			id:                  "Correction",
+			repetitions:         3,
+			submission:          &Submission{IsFirstGuess: false, Grade: GradeEasy},
+			expectedRepetitions: 0,
+			expectedInterval:    1,
+		},
+		{
+			id:                  "Ungraded",
+			repetitions:         3,
+			submission:          &Submission{IsFirstGuess: true},
+			expectedRepetitions: 4,
+			expectedInterval:    8,
+		},
+		{
+			id:                  "Again",
+			repetitions:         3,
+			submission:          &Submission{IsFirstGuess: true, Grade: GradeAgain},
+			expectedRepetitions: 0,
+			expectedInterval:    1,
+		},
+		{
+			id:                  "Hard",
+			repetitions:         3,
+			submission:          &Submission{IsFirstGuess: true, Grade: GradeHard},
+			expectedRepetitions: 3,
+			expectedInterval:    4,
+		},
+		{
+			id:                  "Hard new flashcard",
+			repetitions:         0,
+			submission:          &Submission{IsFirstGuess: true, Grade: GradeHard},
+			expectedRepetitions: 0,
+			expectedInterval:    1,
+		},
+		{
+			id:                  "Good",
+			repetitions:         3,
+			submission:          &Submission{IsFirstGuess: true, Grade: GradeGood},
+			expectedRepetitions: 4,
+			expectedInterval:    8,
+		},
+		{
+			id:                  "Easy",
+			repetitions:         3,
+			submission:          &Submission{IsFirstGuess: true, Grade: GradeEasy},
+			expectedRepetitions: 5,
+			expectedInterval:    16,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.id, func(t *testing.T) {
+			stats, next := NewDoublingScheduler().Schedule(&Session{}, FlashcardStats{Repetitions: tc.repetitions}, tc.submission)
+			require.Equal(t, tc.expectedRepetitions, stats.Repetitions)
+			require.Equal(t, tc.expectedInterval, next)
+		})
+	}
+}
+
+func TestFuzzInterval(t *testing.T) {
+	testCases := []struct {
+		id          string
+		interval    int
+		fuzz        float64
+		expectedMin int
+		expectedMax int
+	}{
+		{id: "No fuzz", interval: 20, fuzz: 0, expectedMin: 20, expectedMax: 20},
+		{id: "Short interval", interval: 1, fuzz: 0.15, expectedMin: 1, expectedMax: 1},
+		{id: "Long interval", interval: 100, fuzz: 0.15, expectedMin: 85, expectedMax: 115},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.id, func(t *testing.T) {
+			seen := make(map[int]bool)
+			for key := range uint64(100) {
+				fuzzed := fuzzInterval(tc.interval, tc.fuzz, key)
+				require.GreaterOrEqual(t, fuzzed, tc.expectedMin)
+				require.LessOrEqual(t, fuzzed, tc.expectedMax)
+				require.Equal(t, fuzzed, fuzzInterval(tc.interval, tc.fuzz, key))
+				seen[fuzzed] = true
+			}
+			if tc.expectedMin != tc.expectedMax {
+				require.Greater(t, len(seen), 1)
+			}
+		})
+	}
+}
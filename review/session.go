@@ -1,6 +1,22 @@
 package review
 
-import "context"
+import (
+	"context"
+	"time"
+)
+
+// day is the unit of time between reviews for time-based sessions.
+const day = 24 * time.Hour
+
+// Review modes.
+const (
+	// ReviewModeTyped means that the user types the answer, which is checked by the server.
+	ReviewModeTyped = "typed"
+	// ReviewModeReveal means that the user reveals the answer and then grades themselves.
+	ReviewModeReveal = "reveal"
+	// ReviewModeMultipleChoice means that the user picks the answer from several choices.
+	ReviewModeMultipleChoice = "multiple-choice"
+)
 
 // SessionStore stores the state of a review session.
 type SessionStore interface {
@@ -14,16 +30,28 @@ type SessionStore interface {
 	SetFlashcards(ctx context.Context, sessionID string, metadata []*FlashcardMetadata) error
 	// SetFlashcardStats updates a flashcard's stats.
 	SetFlashcardStats(ctx context.Context, sessionID string, flashcardID int64, stats *FlashcardStats) error
+	// GetChoiceCandidates returns up to the specified number of flashcards with
+	// the specified context and up to the specified number of flashcards with
+	// any context, whose answers can be offered as choices. The two may overlap.
+	GetChoiceCandidates(ctx context.Context, sessionID, promptContext string, limit int) ([]*Flashcard, error)
 	// NextReviewed returns a flashcard that is due to be reviewed again.
-	NextReviewed(ctx context.Context, sessionID string, round int) (*Flashcard, error)
+	NextReviewed(ctx context.Context, sessionID string, query *ReviewQuery) (*Flashcard, error)
 	// NextUnreviewed returns a flashcard that has never been reviewed before.
-	NextUnreviewed(ctx context.Context, sessionID string) (*Flashcard, error)
+	NextUnreviewed(ctx context.Context, sessionID string, query *ReviewQuery) (*Flashcard, error)
+	// CountSuspended returns the number of suspended flashcards.
+	CountSuspended(ctx context.Context, sessionID string) (int, error)
+	// SetSuspended suspends or unsuspends a flashcard.
+	SetSuspended(ctx context.Context, sessionID string, flashcardID int64, suspended bool) error
+	// SetBuriedUntil excludes a flashcard from reviews until the specified round (or day, for time-based sessions).
+	SetBuriedUntil(ctx context.Context, sessionID string, flashcardID int64, period int) error
 	// GetSession returns the current session metadata.
 	GetSession(ctx context.Context, sessionID string) (*Session, error)
 	// GetSessions returns the metadata for all existing sessions.
 	GetSessions(ctx context.Context) ([]*Session, error)
 	// SetSession updates the session metadata.
 	SetSession(ctx context.Context, sessionID string, session *Session) error
+	// ReviewLogStore records every submission.
+	ReviewLogStore
 }
 
 // Session represents review session metadata.
@@ -38,6 +66,92 @@ type Session struct {
 	ProficiencyCounts []int `firestore:"proficiencyCounts" json:"proficiencyCounts"`
 	// UnreviewedCount is the number of flashcards that haven't been reviewed yet.
 	UnreviewedCount int `firestore:"unreviewedCount" json:"unreviewedCount"`
+	// NewCount is the number of new flashcards reviewed in the current round (or day).
+	NewCount int `firestore:"newCount,omitempty" json:"newCount,omitempty"`
+	// ReviewCount is the number of previously reviewed flashcards reviewed in the current round (or day).
+	ReviewCount int `firestore:"reviewCount,omitempty" json:"reviewCount,omitempty"`
+	// CountedPeriod is the round (or day) to which NewCount and ReviewCount refer.
+	CountedPeriod int `firestore:"countedPeriod,omitempty" json:"countedPeriod,omitempty"`
+	// SessionOptions configures the behaviour of the session.
+	SessionOptions
+}
+
+// SessionOptions configures the behaviour of a review session.
+type SessionOptions struct {
+	// Scheduler identifies the scheduler that decides when flashcards are due.
+	Scheduler string `firestore:"scheduler,omitempty" json:"scheduler,omitempty"`
+	// TargetRetention is the probability of recall that the FSRS scheduler aims for.
+	TargetRetention float64 `firestore:"targetRetention,omitempty" json:"targetRetention,omitempty"`
+	// FSRSWeights are the FSRS model weights, typically fitted to the session's own review history.
+	FSRSWeights []float64 `firestore:"fsrsWeights,omitempty" json:"fsrsWeights,omitempty"`
+	// BoxCadences specifies how often the flashcards in each box are reviewed, in rounds (Leitner only).
+	BoxCadences []int `firestore:"boxCadences,omitempty" json:"boxCadences,omitempty"`
+	// TimeBased is true if and only if flashcards are due at a certain time rather than
+	// in a certain round, in which case intervals between reviews are measured in days.
+	TimeBased bool `firestore:"timeBased,omitempty" json:"timeBased,omitempty"`
+	// RelearningSteps specifies the intervals, in rounds (or days), between the reviews
+	// of a forgotten flashcard before it graduates back to the scheduler's intervals.
+	RelearningSteps []int `firestore:"relearningSteps,omitempty" json:"relearningSteps,omitempty"`
+	// LeechThreshold is the number of lapses after which a flashcard is marked as a leech.
+	// Leech detection is disabled if it isn't specified.
+	LeechThreshold int `firestore:"leechThreshold,omitempty" json:"leechThreshold,omitempty"`
+	// SuspendLeeches is true if and only if leeches are excluded from reviews.
+	SuspendLeeches bool `firestore:"suspendLeeches,omitempty" json:"suspendLeeches,omitempty"`
+	// NewCardsPerRound limits the number of new flashcards per round (or day, for time-based sessions).
+	// If it isn't specified, a new flashcard is only introduced at the start of each round.
+	NewCardsPerRound int `firestore:"newCardsPerRound,omitempty" json:"newCardsPerRound,omitempty"`
+	// MaxReviewsPerRound limits the number of reviews of previously reviewed flashcards per round
+	// (or day, for time-based sessions). There's no limit if it isn't specified.
+	MaxReviewsPerRound int `firestore:"maxReviewsPerRound,omitempty" json:"maxReviewsPerRound,omitempty"`
+	// Ordering decides which flashcard is reviewed next if several are available.
+	// Defaults to the store's own order.
+	Ordering string `firestore:"ordering,omitempty" json:"ordering,omitempty"`
+	// IntervalFuzz is the fraction by which intervals are randomly lengthened or shortened,
+	// to spread out reviews. Intervals aren't fuzzed if it isn't specified.
+	IntervalFuzz float64 `firestore:"intervalFuzz,omitempty" json:"intervalFuzz,omitempty"`
+	// Seed makes random orderings and interval fuzz reproducible. Generated when creating
+	// the session if it isn't specified.
+	Seed int64 `firestore:"seed,omitempty" json:"seed,omitempty"`
+	// Normalizers are applied, in order, to both the expected and the submitted answers
+	// before comparing them. Answers are compared exactly if none are specified.
+	Normalizers []string `firestore:"normalizers,omitempty" json:"normalizers,omitempty"`
+	// IgnoreAccents is true if and only if answers that only differ from the expected
+	// answer in their accents are accepted, albeit with a warning.
+	IgnoreAccents bool `firestore:"ignoreAccents,omitempty" json:"ignoreAccents,omitempty"`
+	// TypoTolerance is the number of typos (insertions, deletions, substitutions or
+	// transpositions of adjacent characters) that are tolerated per character of the
+	// expected answer, e.g. 0.2 for one typo per five characters. Answers with tolerated
+	// typos are accepted with a warning. Typos aren't tolerated if it isn't specified.
+	TypoTolerance float64 `firestore:"typoTolerance,omitempty" json:"typoTolerance,omitempty"`
+	// ConvertUnitPrefixes is true if and only if numeric answers may be given in units with
+	// different SI prefixes than the expected answer, e.g. "981 cm/s²" instead of "9.81 m/s²".
+	ConvertUnitPrefixes bool `firestore:"convertUnitPrefixes,omitempty" json:"convertUnitPrefixes,omitempty"`
+	// WarningsCountAsFirstGuess is true if and only if an answer that was accepted with
+	// a warning can count as a correct first guess.
+	WarningsCountAsFirstGuess bool `firestore:"warningsCountAsFirstGuess,omitempty" json:"warningsCountAsFirstGuess,omitempty"`
+	// MergeAmbiguousAnswers is true if and only if flashcards with the same prompt and
+	// context, but different answers, are merged into a single flashcard for which all
+	// of the answers must be named. Otherwise, such flashcards are rejected.
+	MergeAmbiguousAnswers bool `firestore:"mergeAmbiguousAnswers,omitempty" json:"mergeAmbiguousAnswers,omitempty"`
+	// ReviewMode determines how flashcards are reviewed. Defaults to ReviewModeTyped.
+	ReviewMode string `firestore:"reviewMode,omitempty" json:"reviewMode,omitempty"`
+	// NumChoices is the number of choices offered for each flashcard, including the answer
+	// (multiple-choice sessions only). Defaults to DefaultNumChoices.
+	NumChoices int `firestore:"numChoices,omitempty" json:"numChoices,omitempty"`
+}
+
+// ReviewQuery specifies which flashcards are due to be reviewed.
+type ReviewQuery struct {
+	// Round is the current round.
+	Round int
+	// TimeBased is true if and only if flashcards are due at a certain time rather than in a certain round.
+	TimeBased bool
+	// Now is the current time.
+	Now time.Time
+	// Ordering decides which flashcard is picked if several are available.
+	Ordering string
+	// Seed makes random orderings reproducible.
+	Seed int64
 }
 
 // NewSession initializes session metadata for the case where no flashcards have been added yet.
@@ -56,3 +170,110 @@ func (session *Session) IncrementProficiency(repetitions int, increment int) {
 	i := min(repetitions, len(session.ProficiencyCounts)-1)
 	session.ProficiencyCounts[i] += increment
 }
+
+// flashcardCount returns the total number of flashcards, whether reviewed or not.
+func (session *Session) flashcardCount() int {
+	count := session.UnreviewedCount
+	for _, c := range session.ProficiencyCounts {
+		count += c
+	}
+	return count
+}
+
+// reviewQuery returns a query for flashcards that are due to be reviewed now.
+func (session *Session) reviewQuery(now time.Time) *ReviewQuery {
+	return &ReviewQuery{
+		Round:     session.Round,
+		TimeBased: session.TimeBased,
+		Now:       now,
+		Ordering:  session.Ordering,
+		Seed:      session.Seed,
+	}
+}
+
+// resetCounts starts counting new flashcards and reviews from zero if the
+// specified round (or day) differs from the one being counted.
+func (session *Session) resetCounts(period int) {
+	if session.CountedPeriod != period {
+		session.NewCount = 0
+		session.ReviewCount = 0
+		session.CountedPeriod = period
+	}
+}
+
+// allowsNew returns true if and only if another new flashcard may be reviewed.
+func (session *Session) allowsNew() bool {
+	if session.NewCardsPerRound == 0 {
+		return session.IsNewRound
+	}
+	return session.NewCount < session.NewCardsPerRound
+}
+
+// allowsReview returns true if and only if another previously reviewed
+// flashcard may be reviewed.
+func (session *Session) allowsReview() bool {
+	return session.MaxReviewsPerRound == 0 || session.ReviewCount < session.MaxReviewsPerRound
+}
+
+// prefersReviewed returns true if and only if previously reviewed flashcards
+// should be considered before new ones.
+func (session *Session) prefersReviewed() bool {
+	return session.Ordering == OrderingInterleaved && session.NewCount > session.ReviewCount
+}
+
+// period returns the current round, or the number of days since the Unix epoch
+// for time-based sessions.
+func (session *Session) period(now time.Time) int {
+	return session.periodAt(session.Round, now)
+}
+
+// periodAt returns the specified round, or the number of days between the Unix
+// epoch and the specified time for time-based sessions.
+func (session *Session) periodAt(round int, t time.Time) int {
+	query := ReviewQuery{Round: round, TimeBased: session.TimeBased, Now: t}
+	return query.period()
+}
+
+// period returns the round, or the number of days since the Unix epoch for
+// time-based sessions.
+func (q *ReviewQuery) period() int {
+	if q.TimeBased {
+		return int(q.Now.Unix() / int64(day.Seconds()))
+	}
+	return q.Round
+}
+
+// isDue returns true if and only if the flashcard is due to be reviewed.
+func (q *ReviewQuery) isDue(stats *FlashcardStats) bool {
+	if q.TimeBased {
+		return !stats.DueAt.After(q.Now)
+	}
+	return stats.NextReview <= q.Round
+}
+
+// numChoices returns the number of choices offered for each flashcard in
+// multiple-choice sessions, falling back to the default if none was specified.
+func (o *SessionOptions) numChoices() int {
+	if o.NumChoices == 0 {
+		return DefaultNumChoices
+	}
+	return o.NumChoices
+}
+
+// targetRetention returns the probability of recall that the FSRS scheduler
+// should aim for, falling back to the default if none was specified.
+func (o *SessionOptions) targetRetention() float64 {
+	if o.TargetRetention == 0 {
+		return DefaultTargetRetention
+	}
+	return o.TargetRetention
+}
+
+// boxCadences returns how often the flashcards in each Leitner box should be
+// reviewed, falling back to the default if none were specified.
+func (o *SessionOptions) boxCadences() []int {
+	if len(o.BoxCadences) == 0 {
+		return DefaultBoxCadences
+	}
+	return o.BoxCadences
+}
@@ -2,11 +2,20 @@ package review
 
 import (
 	"context"
+	"fmt"
+	"slices"
 	"strconv"
+	"strings"
 
 	"github.com/lafeingcrokodil/flashcards/v2/sheets"
 )
 
+// percent is the number of percent in a whole.
+const percent = 100
+
+// PatternPrefix marks an answer cell as a regular expression for accepted answers.
+const PatternPrefix = "re:"
+
 // SheetSource stores flashcard metadata in a Google Sheets spreadsheet.
 type SheetSource struct {
 	// SpreadsheetID uniquely identifies the spreadsheet.
@@ -21,6 +30,20 @@ type SheetSource struct {
 	ContextHeader string `json:"contextHeader"`
 	// AnswerHeader is the name of the column containing the answers.
 	AnswerHeader string `json:"answerHeader"`
+	// AlternativeAnswerHeaders are the names of the columns containing alternative answers (if any).
+	AlternativeAnswerHeaders []string `json:"alternativeAnswerHeaders,omitempty"`
+	// PatternHeader is the name of the column containing regular expressions for further accepted
+	// answers (if any), in which case the answer column contains the answers shown as feedback.
+	PatternHeader string `json:"patternHeader,omitempty"`
+	// AnswerTypeHeader is the name of the column containing the answer types (if any), either
+	// "text" (default) or "numeric".
+	AnswerTypeHeader string `json:"answerTypeHeader,omitempty"`
+	// ToleranceHeader is the name of the column containing the tolerances for numeric answers (if any),
+	// either absolute (e.g. "0.05") or relative (e.g. "1%").
+	ToleranceHeader string `json:"toleranceHeader,omitempty"`
+	// AnswerSeparator separates multiple accepted answers within a cell, e.g. "|" for "colour | color".
+	// Cells aren't split if it isn't specified.
+	AnswerSeparator string `json:"answerSeparator,omitempty"`
 }
 
 // GetAll returns the metadata for all flashcards.
@@ -37,13 +60,109 @@ func (s *SheetSource) GetAll(ctx context.Context) ([]*FlashcardMetadata, error)
 		if err != nil {
 			return nil, err
 		}
+		absoluteTolerance, relativeTolerance, err := parseTolerance(record[s.ToleranceHeader])
+		if err != nil {
+			return nil, fmt.Errorf("flashcard %d: %w", id, err)
+		}
+		answer, alternatives, pattern := s.answers(record)
 		metadata = append(metadata, &FlashcardMetadata{
-			ID:      id,
-			Prompt:  record[s.PromptHeader],
-			Context: record[s.ContextHeader],
-			Answer:  record[s.AnswerHeader],
+			ID:                 id,
+			Prompt:             record[s.PromptHeader],
+			Context:            record[s.ContextHeader],
+			Answer:             answer,
+			AlternativeAnswers: alternatives,
+			Pattern:            pattern,
+			AnswerType:         strings.ToLower(strings.TrimSpace(record[s.AnswerTypeHeader])),
+			AbsoluteTolerance:  absoluteTolerance,
+			RelativeTolerance:  relativeTolerance,
 		})
 	}
 
 	return metadata, nil
 }
+
+// answers returns the canonical answer in the record, which is the first
+// answer in the answer column, any other accepted answers and the pattern for
+// further accepted answers. Patterns can either be specified in the pattern
+// column or in an answer cell starting with PatternPrefix, in which case the
+// whole cell is the pattern, and the next answer is the canonical one (or the
+// pattern itself if there are no other answers).
+func (s *SheetSource) answers(record map[string]string) (answer string, alternatives []string, pattern string) {
+	var answers, patterns []string
+
+	if p := record[s.PatternHeader]; p != "" {
+		patterns = append(patterns, p)
+	}
+
+	cells := append([]string{record[s.AnswerHeader]}, s.alternativeAnswerCells(record)...)
+	for _, cell := range cells {
+		if p, ok := strings.CutPrefix(cell, PatternPrefix); ok {
+			patterns = append(patterns, p)
+			continue
+		}
+		for _, a := range s.split(cell) {
+			if a != "" && !slices.Contains(answers, a) {
+				answers = append(answers, a)
+			}
+		}
+	}
+
+	pattern = strings.Join(patterns, ")|(?:")
+	if len(patterns) > 1 {
+		pattern = "(?:" + pattern + ")"
+	}
+
+	switch len(answers) {
+	case 0:
+		return pattern, nil, pattern
+	case 1:
+		return answers[0], nil, pattern
+	default:
+		return answers[0], answers[1:], pattern
+	}
+}
+
+// alternativeAnswerCells returns the cells in the alternative answer columns.
+func (s *SheetSource) alternativeAnswerCells(record map[string]string) []string {
+	cells := make([]string, 0, len(s.AlternativeAnswerHeaders))
+	for _, header := range s.AlternativeAnswerHeaders {
+		cells = append(cells, record[header])
+	}
+	return cells
+}
+
+// split splits the cell into answers using the answer separator, if any.
+func (s *SheetSource) split(cell string) []string {
+	if s.AnswerSeparator == "" {
+		return []string{cell}
+	}
+
+	answers := strings.Split(cell, s.AnswerSeparator)
+	for i := range answers {
+		answers[i] = strings.TrimSpace(answers[i])
+	}
+
+	return answers
+}
+
+// parseTolerance parses a tolerance, which is relative if it's a percentage
+// and absolute otherwise.
+func parseTolerance(cell string) (absolute, relative float64, err error) {
+	cell = strings.TrimSpace(cell)
+	if cell == "" {
+		return 0, 0, nil
+	}
+
+	percentage, isRelative := strings.CutSuffix(cell, "%")
+
+	tolerance, err := strconv.ParseFloat(strings.TrimSpace(percentage), 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("tolerance %s: %w: %w", cell, ErrInvalidNumber, err)
+	}
+
+	if isRelative {
+		return 0, tolerance / percent, nil
+	}
+
+	return tolerance, 0, nil
+}
@@ -31,3 +31,107 @@ func Test_SheetStore_GetAll(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, expectedFlashcards, flashcards)
 }
+
+func TestSheetSource_answers(t *testing.T) {
+	testCases := []struct {
+		id                   string
+		source               SheetSource
+		record               map[string]string
+		expectedAnswer       string
+		expectedAlternatives []string
+		expectedPattern      string
+	}{
+		{
+			id:             "Single answer",
+			source:         SheetSource{AnswerHeader: "A"},
+			record:         map[string]string{"A": "colour | color"},
+			expectedAnswer: "colour | color",
+		},
+		{
+			id:                   "Separator",
+			source:               SheetSource{AnswerHeader: "A", AnswerSeparator: "|"},
+			record:               map[string]string{"A": "colour | color"},
+			expectedAnswer:       "colour",
+			expectedAlternatives: []string{"color"},
+		},
+		{
+			id:                   "Alternative answer columns",
+			source:               SheetSource{AnswerHeader: "A", AlternativeAnswerHeaders: []string{"B", "C"}},
+			record:               map[string]string{"A": "colour", "B": "", "C": "color"},
+			expectedAnswer:       "colour",
+			expectedAlternatives: []string{"color"},
+		},
+		{
+			id:                   "Duplicates",
+			source:               SheetSource{AnswerHeader: "A", AlternativeAnswerHeaders: []string{"B"}, AnswerSeparator: ","},
+			record:               map[string]string{"A": "grey, gray", "B": "gray,,ash"},
+			expectedAnswer:       "grey",
+			expectedAlternatives: []string{"gray", "ash"},
+		},
+		{
+			id:              "Pattern column",
+			source:          SheetSource{AnswerHeader: "A", PatternHeader: "P"},
+			record:          map[string]string{"A": "the capital", "P": "(the )?capital"},
+			expectedAnswer:  "the capital",
+			expectedPattern: "(the )?capital",
+		},
+		{
+			id:              "Pattern prefix",
+			source:          SheetSource{AnswerHeader: "A", AlternativeAnswerHeaders: []string{"B"}, AnswerSeparator: "|"},
+			record:          map[string]string{"A": "re:(the )?(capital|seat)", "B": "the capital"},
+			expectedAnswer:  "the capital",
+			expectedPattern: "(the )?(capital|seat)",
+		},
+		{
+			id:              "Pattern prefix without other answers",
+			source:          SheetSource{AnswerHeader: "A"},
+			record:          map[string]string{"A": "re:colou?r"},
+			expectedAnswer:  "colou?r",
+			expectedPattern: "colou?r",
+		},
+		{
+			id:              "Several patterns",
+			source:          SheetSource{AnswerHeader: "A", AlternativeAnswerHeaders: []string{"B"}, PatternHeader: "P"},
+			record:          map[string]string{"A": "grey", "B": "re:gr[ae]y", "P": "ash( grey)?"},
+			expectedAnswer:  "grey",
+			expectedPattern: "(?:ash( grey)?)|(?:gr[ae]y)",
+		},
+		{
+			id:     "No answer",
+			source: SheetSource{AnswerHeader: "A", AnswerSeparator: "|"},
+			record: map[string]string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		answer, alternatives, pattern := tc.source.answers(tc.record)
+		require.Equal(t, tc.expectedAnswer, answer, tc.id)
+		require.Equal(t, tc.expectedAlternatives, alternatives, tc.id)
+		require.Equal(t, tc.expectedPattern, pattern, tc.id)
+	}
+}
+
+func TestParseTolerance(t *testing.T) {
+	testCases := []struct {
+		cell             string
+		expectedAbsolute float64
+		expectedRelative float64
+		expectedErr      error
+	}{
+		{cell: ""},
+		{cell: "0.05", expectedAbsolute: 0.05},
+		{cell: " 1.5 % ", expectedRelative: 0.015},
+		{cell: "a lot", expectedErr: ErrInvalidNumber},
+	}
+
+	for _, tc := range testCases {
+		absolute, relative, err := parseTolerance(tc.cell)
+		if tc.expectedErr != nil {
+			require.ErrorIs(t, err, tc.expectedErr, tc.cell)
+			continue
+		}
+		require.NoError(t, err, tc.cell)
+		require.InDelta(t, tc.expectedAbsolute, absolute, 1e-12, tc.cell)
+		require.InDelta(t, tc.expectedRelative, relative, 1e-12, tc.cell)
+	}
+}
@@ -0,0 +1,74 @@
+package review
+
+import "math"
+
+const (
+	// SM2SchedulerName identifies the SM2Scheduler.
+	SM2SchedulerName = "sm2"
+
+	sm2InitialEaseFactor = 2.5
+	sm2MinEaseFactor     = 1.3
+	sm2SecondInterval    = 6
+	sm2MaxQuality        = 5
+	sm2PassingQuality    = 3
+	sm2Precision         = 100
+)
+
+// SM2Scheduler implements the SuperMemo 2 algorithm, which adapts the interval
+// between reviews to each flashcard by keeping track of an ease factor.
+type SM2Scheduler struct{}
+
+// NewSM2Scheduler returns a new SM2Scheduler.
+func NewSM2Scheduler() *SM2Scheduler {
+	return &SM2Scheduler{}
+}
+
+// Name uniquely identifies the scheduler.
+func (s *SM2Scheduler) Name() string {
+	return SM2SchedulerName
+}
+
+// Schedule returns the updated stats for a flashcard that has just been
+// answered correctly, along with the number of rounds until its next review.
+func (s *SM2Scheduler) Schedule(_ *Session, stats FlashcardStats, submission *Submission) (FlashcardStats, int) {
+	quality := sm2Quality(submission)
+
+	if stats.EaseFactor == 0 {
+		stats.EaseFactor = sm2InitialEaseFactor
+	}
+
+	switch {
+	case quality < sm2PassingQuality:
+		stats.Repetitions = 0
+		stats.Interval = 1
+	case stats.Repetitions == 0:
+		stats.Interval = 1
+	case stats.Repetitions == 1:
+		stats.Interval = sm2SecondInterval
+	default:
+		stats.Interval = int(math.Round(float64(stats.Interval) * stats.EaseFactor))
+	}
+
+	if quality >= sm2PassingQuality {
+		stats.Repetitions++
+	}
+
+	stats.EaseFactor = sm2EaseFactor(stats.EaseFactor, quality)
+
+	return stats, stats.Interval
+}
+
+// sm2Quality rates the quality of a submission on a scale from 0 to 5, where
+// GradeAgain corresponds to 2 (incorrect, but the answer seemed easy to recall)
+// and the other grades correspond to 3 to 5.
+func sm2Quality(submission *Submission) int {
+	return int(submission.grade()) + 1
+}
+
+// sm2EaseFactor returns the updated ease factor, rounded to two decimal places.
+func sm2EaseFactor(easeFactor float64, quality int) float64 {
+	d := float64(sm2MaxQuality - quality)
+	easeFactor += 0.1 - d*(0.08+d*0.02) //nolint:mnd // SM-2 constants
+	easeFactor = math.Round(easeFactor*sm2Precision) / sm2Precision
+	return max(easeFactor, sm2MinEaseFactor)
+}
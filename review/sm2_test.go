@@ -0,0 +1,84 @@
+package review
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSM2Scheduler_Schedule(t *testing.T) {
+	updates := []struct {
+		id            string
+		isFirstGuess  bool
+		round         int
+		expectedStats FlashcardStats
+	}{
+		{
+			id:            "Correct first review",
+			isFirstGuess:  true,
+			round:         0,
+			expectedStats: FlashcardStats{ViewCount: 1, Repetitions: 1, NextReview: 1, EaseFactor: 2.5, Interval: 1},
+		},
+		{
+			id:            "Correct second review",
+			isFirstGuess:  true,
+			round:         1,
+			expectedStats: FlashcardStats{ViewCount: 2, Repetitions: 2, NextReview: 7, EaseFactor: 2.5, Interval: 6},
+		},
+		{
+			id:            "Correct third review",
+			isFirstGuess:  true,
+			round:         7,
+			expectedStats: FlashcardStats{ViewCount: 3, Repetitions: 3, NextReview: 22, EaseFactor: 2.5, Interval: 15},
+		},
+		{
+			id:            "Corrected fourth review",
+			isFirstGuess:  false,
+			round:         22,
+			expectedStats: FlashcardStats{ViewCount: 4, Repetitions: 0, NextReview: 23, EaseFactor: 2.18, Interval: 1, Lapses: 1},
+		},
+		{
+			id:            "Correct fifth review",
+			isFirstGuess:  true,
+			round:         23,
+			expectedStats: FlashcardStats{ViewCount: 5, Repetitions: 1, NextReview: 24, EaseFactor: 2.18, Interval: 1, Lapses: 1},
+		},
+	}
+
+	f := &Flashcard{Metadata: flashcardMetadata(1)}
+	scheduler := NewSM2Scheduler()
+
+	for _, update := range updates {
+		submission := &Submission{Answer: f.Metadata.Answer, IsFirstGuess: update.isFirstGuess}
+		match := f.Submit(submission, &Session{Round: update.round}, scheduler)
+		require.True(t, match.IsCorrect(), update.id)
+		require.Equal(t, update.expectedStats, f.Stats, update.id)
+	}
+}
+
+func TestSM2Scheduler_minEaseFactor(t *testing.T) {
+	stats := FlashcardStats{EaseFactor: 1.4}
+	stats, next := NewSM2Scheduler().Schedule(&Session{}, stats, &Submission{IsFirstGuess: false})
+	require.Equal(t, 1, next)
+	require.InDelta(t, 1.3, stats.EaseFactor, 0)
+}
+
+func TestSM2Scheduler_grades(t *testing.T) {
+	testCases := []struct {
+		grade              Grade
+		expectedEaseFactor float64
+		expectedInterval   int
+	}{
+		{grade: GradeAgain, expectedEaseFactor: 2.18, expectedInterval: 1},
+		{grade: GradeHard, expectedEaseFactor: 2.36, expectedInterval: 15},
+		{grade: GradeGood, expectedEaseFactor: 2.5, expectedInterval: 15},
+		{grade: GradeEasy, expectedEaseFactor: 2.6, expectedInterval: 15},
+	}
+
+	for _, tc := range testCases {
+		stats := FlashcardStats{Repetitions: 2, EaseFactor: 2.5, Interval: 6}
+		stats, next := NewSM2Scheduler().Schedule(&Session{}, stats, &Submission{IsFirstGuess: true, Grade: tc.grade})
+		require.InDelta(t, tc.expectedEaseFactor, stats.EaseFactor, 1e-9, tc.grade)
+		require.Equal(t, tc.expectedInterval, next, tc.grade)
+	}
+}
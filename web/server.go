@@ -2,6 +2,7 @@
 package web
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -12,6 +13,10 @@ import (
 	"github.com/lafeingcrokodil/flashcards/v2/review"
 )
 
+// maxSubmissionSize is the maximum size, in bytes, of a submission's payload,
+// which bounds the length of the submitted answer.
+const maxSubmissionSize = 4096
+
 var (
 	// ErrMissingFlashcardID is thrown if a request is missing a flashcard ID.
 	ErrMissingFlashcardID = errors.New("missing flashcard ID")
@@ -19,6 +24,18 @@ var (
 	ErrMissingSessionID = errors.New("missing session ID")
 )
 
+// CreateSessionRequest is the payload for creating a new session.
+type CreateSessionRequest struct {
+	review.SheetSource
+	review.SessionOptions
+}
+
+// GradeRequest is the payload for grading a flashcard without checking an answer.
+type GradeRequest struct {
+	// Grade rates how well the flashcard was recalled, from 1 (again) to 4 (easy).
+	Grade review.Grade `json:"grade"`
+}
+
 // Server is a web server for reviewing flashcards.
 type Server struct {
 	reviewer             *review.Reviewer
@@ -28,7 +45,12 @@ type Server struct {
 // New initializes a new server.
 func New(store review.SessionStore, numProficiencyLevels int) (*Server, error) {
 	return &Server{
-		reviewer:             review.NewReviewer(store),
+		reviewer: review.NewReviewer(store,
+			review.NewDoublingScheduler(),
+			review.NewSM2Scheduler(),
+			review.NewFSRSScheduler(),
+			review.NewLeitnerScheduler(),
+		),
 		numProficiencyLevels: numProficiencyLevels,
 	}, nil
 }
@@ -47,22 +69,38 @@ func (s *Server) getRouter() *mux.Router {
 	r.HandleFunc("/sessions", s.handleGetSessions).Methods("GET")
 	r.HandleFunc("/sessions/{sid}", s.handleGetSession).Methods("GET")
 	r.HandleFunc("/sessions/{sid}/flashcards", s.handleGetFlashcards).Methods("GET")
+	r.HandleFunc("/sessions/{sid}/leeches", s.handleGetLeeches).Methods("GET")
+	r.HandleFunc("/sessions/{sid}/log", s.handleGetReviewLog).Methods("GET")
+	r.HandleFunc("/sessions/{sid}/undo", s.handleUndo).Methods("POST")
+	r.HandleFunc("/sessions/{sid}/optimize", s.handleOptimize).Methods("POST")
+	r.HandleFunc("/sessions/{sid}/rounds/next", s.handleNextRound).Methods("POST")
 	r.HandleFunc("/sessions/{sid}/flashcards/next", s.handleNextFlashcard).Methods("POST")
 	r.HandleFunc("/sessions/{sid}/flashcards/sync", s.handleSyncFlashcards).Methods("POST")
 	r.HandleFunc("/sessions/{sid}/flashcards/{fid}/submit", s.handleSubmitFlashcard).Methods("POST")
+	r.HandleFunc("/sessions/{sid}/flashcards/{fid}/grade", s.handleGradeFlashcard).Methods("POST")
+	r.HandleFunc("/sessions/{sid}/flashcards/{fid}/reveal", s.handleRevealFlashcard).Methods("POST")
+	r.HandleFunc("/sessions/{sid}/flashcards/{fid}/suspend", s.handleSuspendFlashcard).Methods("POST")
+	r.HandleFunc("/sessions/{sid}/flashcards/{fid}/unsuspend", s.handleUnsuspendFlashcard).Methods("POST")
+	r.HandleFunc("/sessions/{sid}/flashcards/{fid}/bury", s.handleBuryFlashcard).Methods("POST")
 	r.PathPrefix("/").Handler(http.FileServer(http.Dir("./public")))
 	return r
 }
 
 func (s *Server) handleCreateSession(w http.ResponseWriter, req *http.Request) {
-	var source review.SheetSource
-	err := json.NewDecoder(req.Body).Decode(&source)
+	var payload CreateSessionRequest
+	err := json.NewDecoder(req.Body).Decode(&payload)
 	if err != nil {
 		sendError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	session, err := s.reviewer.CreateSession(req.Context(), &source, s.numProficiencyLevels)
+	session, err := s.reviewer.CreateSession(req.Context(), &payload.SheetSource, s.numProficiencyLevels, &payload.SessionOptions)
+	if errors.Is(err, review.ErrUnknownScheduler) ||
+		errors.Is(err, review.ErrInvalidOptions) ||
+		isInvalidMetadata(err) {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
 	if err != nil {
 		sendError(w, http.StatusInternalServerError, err)
 		return
@@ -110,6 +148,89 @@ func (s *Server) handleGetFlashcards(w http.ResponseWriter, req *http.Request) {
 	sendResponse(w, http.StatusOK, flashcards)
 }
 
+func (s *Server) handleGetLeeches(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	sessionID, ok := vars["sid"]
+	if !ok {
+		sendError(w, http.StatusBadRequest, ErrMissingSessionID)
+		return
+	}
+	leeches, err := s.reviewer.GetLeeches(req.Context(), sessionID)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, err)
+		return
+	}
+	sendResponse(w, http.StatusOK, leeches)
+}
+
+func (s *Server) handleGetReviewLog(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	sessionID, ok := vars["sid"]
+	if !ok {
+		sendError(w, http.StatusBadRequest, ErrMissingSessionID)
+		return
+	}
+	entries, err := s.reviewer.GetReviewLog(req.Context(), sessionID)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, err)
+		return
+	}
+	sendResponse(w, http.StatusOK, entries)
+}
+
+func (s *Server) handleUndo(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	sessionID, ok := vars["sid"]
+	if !ok {
+		sendError(w, http.StatusBadRequest, ErrMissingSessionID)
+		return
+	}
+	flashcard, err := s.reviewer.Undo(req.Context(), sessionID)
+	if errors.Is(err, review.ErrNothingToUndo) {
+		sendError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, err)
+		return
+	}
+	sendResponse(w, http.StatusOK, flashcard)
+}
+
+func (s *Server) handleOptimize(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	sessionID, ok := vars["sid"]
+	if !ok {
+		sendError(w, http.StatusBadRequest, ErrMissingSessionID)
+		return
+	}
+	session, err := s.reviewer.OptimizeFSRS(req.Context(), sessionID)
+	if errors.Is(err, review.ErrInsufficientHistory) {
+		sendError(w, http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, err)
+		return
+	}
+	sendResponse(w, http.StatusOK, session)
+}
+
+func (s *Server) handleNextRound(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	sessionID, ok := vars["sid"]
+	if !ok {
+		sendError(w, http.StatusBadRequest, ErrMissingSessionID)
+		return
+	}
+	session, err := s.reviewer.NextRound(req.Context(), sessionID)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, err)
+		return
+	}
+	sendResponse(w, http.StatusOK, session)
+}
+
 func (s *Server) handleNextFlashcard(w http.ResponseWriter, req *http.Request) {
 	vars := mux.Vars(req)
 	sessionID, ok := vars["sid"]
@@ -118,6 +239,10 @@ func (s *Server) handleNextFlashcard(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 	flashcard, err := s.reviewer.NextFlashcard(req.Context(), sessionID)
+	if errors.Is(err, review.ErrNothingDue) || errors.Is(err, review.ErrLimitReached) {
+		sendError(w, http.StatusNotFound, err)
+		return
+	}
 	if err != nil {
 		sendError(w, http.StatusInternalServerError, err)
 		return
@@ -141,6 +266,10 @@ func (s *Server) handleSyncFlashcards(w http.ResponseWriter, req *http.Request)
 	}
 
 	session, err := s.reviewer.SyncFlashcards(req.Context(), sessionID, &source)
+	if isInvalidMetadata(err) {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
 	if err != nil {
 		sendError(w, http.StatusInternalServerError, err)
 		return
@@ -149,43 +278,143 @@ func (s *Server) handleSyncFlashcards(w http.ResponseWriter, req *http.Request)
 }
 
 func (s *Server) handleSubmitFlashcard(w http.ResponseWriter, req *http.Request) {
-	vars := mux.Vars(req)
+	sessionID, flashcardID, err := flashcardVars(req)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
 
-	sessionID, ok := vars["sid"]
-	if !ok {
-		sendError(w, http.StatusBadRequest, ErrMissingSessionID)
+	var submission review.Submission
+	err = json.NewDecoder(http.MaxBytesReader(w, req.Body, maxSubmissionSize)).Decode(&submission)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		sendError(w, http.StatusRequestEntityTooLarge, err)
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	fid, ok := vars["fid"]
-	if !ok {
-		sendError(w, http.StatusBadRequest, ErrMissingFlashcardID)
+	result, err := s.reviewer.Submit(req.Context(), sessionID, flashcardID, &submission)
+	if errors.Is(err, review.ErrInvalidGrade) || errors.Is(err, review.ErrInvalidChoice) {
+		sendError(w, http.StatusBadRequest, err)
 		return
 	}
+	if errors.Is(err, review.ErrNotFound) {
+		sendError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, err)
+		return
+	}
+	sendResponse(w, http.StatusOK, result)
+}
 
-	flashcardID, err := strconv.ParseInt(fid, 10, 64)
+func (s *Server) handleGradeFlashcard(w http.ResponseWriter, req *http.Request) {
+	sessionID, flashcardID, err := flashcardVars(req)
 	if err != nil {
 		sendError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	var submission review.Submission
-	err = json.NewDecoder(req.Body).Decode(&submission)
+	var payload GradeRequest
+	err = json.NewDecoder(req.Body).Decode(&payload)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	session, err := s.reviewer.Grade(req.Context(), sessionID, flashcardID, payload.Grade)
+	if errors.Is(err, review.ErrInvalidGrade) || errors.Is(err, review.ErrNotGradable) {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+	if errors.Is(err, review.ErrNotRevealed) {
+		sendError(w, http.StatusConflict, err)
+		return
+	}
+	if errors.Is(err, review.ErrNotFound) {
+		sendError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, err)
+		return
+	}
+	sendResponse(w, http.StatusOK, session)
+}
+
+func (s *Server) handleRevealFlashcard(w http.ResponseWriter, req *http.Request) {
+	s.handleFlashcardAction(w, req, s.reviewer.Reveal)
+}
+
+func (s *Server) handleSuspendFlashcard(w http.ResponseWriter, req *http.Request) {
+	s.handleFlashcardAction(w, req, s.reviewer.Suspend)
+}
+
+func (s *Server) handleUnsuspendFlashcard(w http.ResponseWriter, req *http.Request) {
+	s.handleFlashcardAction(w, req, s.reviewer.Unsuspend)
+}
+
+func (s *Server) handleBuryFlashcard(w http.ResponseWriter, req *http.Request) {
+	s.handleFlashcardAction(w, req, s.reviewer.Bury)
+}
+
+// handleFlashcardAction applies an action to the flashcard identified by the
+// request path and responds with the updated flashcard.
+func (s *Server) handleFlashcardAction(
+	w http.ResponseWriter,
+	req *http.Request,
+	action func(ctx context.Context, sessionID string, flashcardID int64) (*review.Flashcard, error),
+) {
+	sessionID, flashcardID, err := flashcardVars(req)
 	if err != nil {
 		sendError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	session, ok, err := s.reviewer.Submit(req.Context(), sessionID, flashcardID, &submission)
+	flashcard, err := action(req.Context(), sessionID, flashcardID)
+	if errors.Is(err, review.ErrNotFound) {
+		sendError(w, http.StatusNotFound, err)
+		return
+	}
 	if err != nil {
 		sendError(w, http.StatusInternalServerError, err)
 		return
 	}
-	if ok {
-		sendResponse(w, http.StatusOK, session)
-	} else {
-		sendResponse(w, http.StatusNotModified, nil)
+	sendResponse(w, http.StatusOK, flashcard)
+}
+
+// flashcardVars returns the session ID and flashcard ID from the request path.
+func flashcardVars(req *http.Request) (string, int64, error) {
+	vars := mux.Vars(req)
+
+	sessionID, ok := vars["sid"]
+	if !ok {
+		return "", 0, ErrMissingSessionID
 	}
+
+	fid, ok := vars["fid"]
+	if !ok {
+		return "", 0, ErrMissingFlashcardID
+	}
+
+	flashcardID, err := strconv.ParseInt(fid, 10, 64)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return sessionID, flashcardID, nil
+}
+
+// isInvalidMetadata returns true if and only if the error was caused by
+// flashcard metadata whose answers can't be checked.
+func isInvalidMetadata(err error) bool {
+	return errors.Is(err, review.ErrInvalidPattern) ||
+		errors.Is(err, review.ErrInvalidNumber) ||
+		errors.Is(err, review.ErrUnknownAnswerType)
 }
 
 func sendError(w http.ResponseWriter, statusCode int, err error) {
@@ -194,11 +423,6 @@ func sendError(w http.ResponseWriter, statusCode int, err error) {
 }
 
 func sendResponse(w http.ResponseWriter, statusCode int, data any) {
-	if statusCode == http.StatusNotModified {
-		w.WriteHeader(statusCode)
-		return
-	}
-
 	// We're taking a calculated risk here of assuming that the encoding will
 	// never fail, so we don't bother implementing the error handling in a way
 	// that would allow sending an error response to the client. We just add
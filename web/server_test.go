@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/gorilla/mux"
@@ -26,12 +27,22 @@ func TestServer(t *testing.T) {
 
 	session := testCreateSession(t, router)
 	testInvalidFlashcardID(t, router, session.ID)
+	testMissingFlashcard(t, router, session.ID)
+	testOversizedSubmission(t, router, session.ID)
 	testGetSession(t, router, session.ID)
 	testGetSessions(t, router, session.ID)
 	testGetFlashcards(t, router, session.ID)
+	testGetLeeches(t, router, session.ID)
 	testNextFlashcard(t, router, session.ID)
 	testSyncFlashcards(t, router, session.ID)
 	testSubmitFlashcard(t, router, session.ID)
+	testGradeFlashcard(t, router, session.ID)
+	testGetReviewLog(t, router, session.ID)
+	testRevealFlashcard(t, router, session.ID)
+	testSuspendFlashcard(t, router, session.ID)
+	testUndo(t, router, session.ID)
+	testOptimize(t, router, session.ID)
+	testNextRound(t, router, session.ID)
 }
 
 func testCreateSession(t *testing.T, router *mux.Router) review.Session {
@@ -39,6 +50,7 @@ func testCreateSession(t *testing.T, router *mux.Router) review.Session {
 		IsNewRound:        true,
 		ProficiencyCounts: []int{0, 0, 0},
 		UnreviewedCount:   4,
+		SessionOptions:    review.SessionOptions{Scheduler: review.DoublingSchedulerName},
 	}
 
 	source := &review.SheetSource{
@@ -78,12 +90,56 @@ func testInvalidFlashcardID(t *testing.T, router *mux.Router, sessionID string)
 	require.Equal(t, http.StatusBadRequest, rec.Code)
 }
 
+func testMissingFlashcard(t *testing.T, router *mux.Router, sessionID string) {
+	for _, action := range []string{"reveal", "suspend", "unsuspend", "bury"} {
+		endpoint := fmt.Sprintf("/sessions/%s/flashcards/99/%s", sessionID, action)
+		req := httptest.NewRequest("POST", endpoint, nil)
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusNotFound, rec.Code, action)
+	}
+
+	body, err := json.Marshal(review.Submission{Answer: "A99", IsFirstGuess: true})
+	require.NoError(t, err)
+
+	endpoint := fmt.Sprintf("/sessions/%s/flashcards/99/submit", sessionID)
+	req := httptest.NewRequest("POST", endpoint, bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	body, err = json.Marshal(GradeRequest{Grade: review.GradeGood})
+	require.NoError(t, err)
+
+	endpoint = fmt.Sprintf("/sessions/%s/flashcards/99/grade", sessionID)
+	req = httptest.NewRequest("POST", endpoint, bytes.NewReader(body))
+	rec = httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func testOversizedSubmission(t *testing.T, router *mux.Router, sessionID string) {
+	body, err := json.Marshal(review.Submission{Answer: strings.Repeat("x", maxSubmissionSize)})
+	require.NoError(t, err)
+
+	endpoint := fmt.Sprintf("/sessions/%s/flashcards/1/submit", sessionID)
+	req := httptest.NewRequest("POST", endpoint, bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+}
+
 func testGetSession(t *testing.T, router *mux.Router, sessionID string) {
 	expectedSession := &review.Session{
 		ID:                sessionID,
 		IsNewRound:        true,
 		ProficiencyCounts: []int{0, 0, 0},
 		UnreviewedCount:   4,
+		SessionOptions:    review.SessionOptions{Scheduler: review.DoublingSchedulerName},
 	}
 
 	req := httptest.NewRequest("GET", "/sessions/"+sessionID, nil)
@@ -104,6 +160,7 @@ func testGetSessions(t *testing.T, router *mux.Router, sessionID string) {
 		IsNewRound:        true,
 		ProficiencyCounts: []int{0, 0, 0},
 		UnreviewedCount:   4,
+		SessionOptions:    review.SessionOptions{Scheduler: review.DoublingSchedulerName},
 	}
 
 	req := httptest.NewRequest("GET", "/sessions", nil)
@@ -139,6 +196,20 @@ func testGetFlashcards(t *testing.T, router *mux.Router, sessionID string) {
 	require.Equal(t, expectedFlashcards, flashcards)
 }
 
+func testGetLeeches(t *testing.T, router *mux.Router, sessionID string) {
+	endpoint := fmt.Sprintf("/sessions/%s/leeches", sessionID)
+	req := httptest.NewRequest("GET", endpoint, nil)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var flashcards []*review.Flashcard
+	err := json.NewDecoder(rec.Body).Decode(&flashcards)
+	require.NoError(t, err)
+	require.Empty(t, flashcards)
+}
+
 func testNextFlashcard(t *testing.T, router *mux.Router, sessionID string) {
 	expectedFlashcard := &review.Flashcard{
 		Metadata: review.FlashcardMetadata{ID: 1, Prompt: "P1", Answer: "A1", Context: "C1"},
@@ -163,6 +234,7 @@ func testSyncFlashcards(t *testing.T, router *mux.Router, sessionID string) {
 		IsNewRound:        true,
 		ProficiencyCounts: []int{0, 0, 0},
 		UnreviewedCount:   4,
+		SessionOptions:    review.SessionOptions{Scheduler: review.DoublingSchedulerName},
 	}
 
 	source := &review.SheetSource{
@@ -191,38 +263,102 @@ func testSyncFlashcards(t *testing.T, router *mux.Router, sessionID string) {
 }
 
 func testSubmitFlashcard(t *testing.T, router *mux.Router, sessionID string) {
+	testCases := []struct {
+		id             string
+		flashcardID    int64
+		submission     *review.Submission
+		expectedResult *review.SubmitResult
+	}{
+		{
+			id:          "Incorrect answer",
+			flashcardID: 1,
+			submission:  &review.Submission{Answer: "X", IsFirstGuess: true},
+			expectedResult: &review.SubmitResult{
+				Session: &review.Session{
+					ID:                sessionID,
+					IsNewRound:        true,
+					ProficiencyCounts: []int{0, 0, 0},
+					UnreviewedCount:   4,
+					SessionOptions:    review.SessionOptions{Scheduler: review.DoublingSchedulerName},
+				},
+				Stats:     review.FlashcardStats{IncorrectCount: 1, IncorrectAnswers: []string{"X"}},
+				IsCorrect: false,
+				Match:     &review.Match{Outcome: review.OutcomeWrong},
+			},
+		},
+		{
+			id:          "Correct answer",
+			flashcardID: 1,
+			submission:  &review.Submission{Answer: "A1", IsFirstGuess: false},
+			expectedResult: &review.SubmitResult{
+				Session: &review.Session{
+					ID:                sessionID,
+					IsNewRound:        false,
+					ProficiencyCounts: []int{1, 0, 0},
+					UnreviewedCount:   3,
+					NewCount:          1,
+					SessionOptions:    review.SessionOptions{Scheduler: review.DoublingSchedulerName},
+				},
+				Stats:     review.FlashcardStats{ViewCount: 1, NextReview: 1, IncorrectCount: 1},
+				IsCorrect: true,
+				Match:     &review.Match{Outcome: review.OutcomeCorrect},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		body, err := json.Marshal(tc.submission)
+		require.NoError(t, err, tc.id)
+
+		endpoint := fmt.Sprintf("/sessions/%s/flashcards/%d/submit", sessionID, tc.flashcardID)
+		req := httptest.NewRequest("POST", endpoint, bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code, tc.id)
+
+		var result review.SubmitResult
+		err = json.NewDecoder(rec.Body).Decode(&result)
+		require.NoError(t, err, tc.id)
+		require.Equal(t, tc.expectedResult, &result, tc.id)
+	}
+}
+
+func testGradeFlashcard(t *testing.T, router *mux.Router, sessionID string) {
 	testCases := []struct {
 		id                 string
 		flashcardID        int64
-		submission         *review.Submission
+		grade              review.Grade
 		expectedStatusCode int
 		expectedSession    *review.Session
 	}{
 		{
-			id:                 "Incorrect answer",
-			flashcardID:        1,
-			submission:         &review.Submission{Answer: "X", IsFirstGuess: true},
-			expectedStatusCode: 304,
+			id:                 "Invalid grade",
+			flashcardID:        2,
+			grade:              5,
+			expectedStatusCode: 400,
 		},
 		{
-			id:                 "Correct answer",
-			flashcardID:        1,
-			submission:         &review.Submission{Answer: "A1", IsFirstGuess: false},
+			id:                 "Valid grade",
+			flashcardID:        2,
+			grade:              review.GradeGood,
 			expectedStatusCode: 200,
 			expectedSession: &review.Session{
 				ID:                sessionID,
 				IsNewRound:        false,
-				ProficiencyCounts: []int{1, 0, 0},
-				UnreviewedCount:   3,
+				ProficiencyCounts: []int{1, 1, 0},
+				UnreviewedCount:   2,
+				NewCount:          2,
+				SessionOptions:    review.SessionOptions{Scheduler: review.DoublingSchedulerName},
 			},
 		},
 	}
 
 	for _, tc := range testCases {
-		body, err := json.Marshal(tc.submission)
+		body, err := json.Marshal(&GradeRequest{Grade: tc.grade})
 		require.NoError(t, err, tc.id)
 
-		endpoint := fmt.Sprintf("/sessions/%s/flashcards/%d/submit", sessionID, tc.flashcardID)
+		endpoint := fmt.Sprintf("/sessions/%s/flashcards/%d/grade", sessionID, tc.flashcardID)
 		req := httptest.NewRequest("POST", endpoint, bytes.NewReader(body))
 		rec := httptest.NewRecorder()
 
@@ -237,3 +373,109 @@ func testSubmitFlashcard(t *testing.T, router *mux.Router, sessionID string) {
 		}
 	}
 }
+
+func testRevealFlashcard(t *testing.T, router *mux.Router, sessionID string) {
+	expectedFlashcard := &review.Flashcard{
+		Metadata: review.FlashcardMetadata{ID: 3, Prompt: "P1", Answer: "A3"},
+		Stats:    review.FlashcardStats{Revealed: true},
+	}
+
+	endpoint := fmt.Sprintf("/sessions/%s/flashcards/3/reveal", sessionID)
+	req := httptest.NewRequest("POST", endpoint, nil)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var flashcard review.Flashcard
+	err := json.NewDecoder(rec.Body).Decode(&flashcard)
+	require.NoError(t, err)
+	require.Equal(t, expectedFlashcard, &flashcard)
+}
+
+func testSuspendFlashcard(t *testing.T, router *mux.Router, sessionID string) {
+	testCases := []struct {
+		action        string
+		expectedStats review.FlashcardStats
+	}{
+		{action: "suspend", expectedStats: review.FlashcardStats{Suspended: true}},
+		{action: "unsuspend", expectedStats: review.FlashcardStats{}},
+		{action: "bury", expectedStats: review.FlashcardStats{BuriedUntil: 1}},
+	}
+
+	for _, tc := range testCases {
+		endpoint := fmt.Sprintf("/sessions/%s/flashcards/4/%s", sessionID, tc.action)
+		req := httptest.NewRequest("POST", endpoint, nil)
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code, tc.action)
+
+		var flashcard review.Flashcard
+		err := json.NewDecoder(rec.Body).Decode(&flashcard)
+		require.NoError(t, err, tc.action)
+		require.Equal(t, tc.expectedStats, flashcard.Stats, tc.action)
+	}
+}
+
+func testGetReviewLog(t *testing.T, router *mux.Router, sessionID string) {
+	expectedFlashcardIDs := []int64{1, 1, 2}
+
+	endpoint := fmt.Sprintf("/sessions/%s/log", sessionID)
+	req := httptest.NewRequest("GET", endpoint, nil)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var entries []*review.ReviewLogEntry
+	err := json.NewDecoder(rec.Body).Decode(&entries)
+	require.NoError(t, err)
+	require.Len(t, entries, len(expectedFlashcardIDs))
+
+	for i, entry := range entries {
+		require.Equal(t, expectedFlashcardIDs[i], entry.FlashcardID, i)
+	}
+}
+
+func testUndo(t *testing.T, router *mux.Router, sessionID string) {
+	expectedFlashcard := &review.Flashcard{
+		Metadata: review.FlashcardMetadata{ID: 2, Prompt: "P1", Answer: "A2", Context: "C2"},
+	}
+
+	endpoint := fmt.Sprintf("/sessions/%s/undo", sessionID)
+	req := httptest.NewRequest("POST", endpoint, nil)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var flashcard review.Flashcard
+	err := json.NewDecoder(rec.Body).Decode(&flashcard)
+	require.NoError(t, err)
+	require.Equal(t, expectedFlashcard, &flashcard)
+}
+
+func testOptimize(t *testing.T, router *mux.Router, sessionID string) {
+	endpoint := fmt.Sprintf("/sessions/%s/optimize", sessionID)
+	req := httptest.NewRequest("POST", endpoint, nil)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusConflict, rec.Code)
+}
+
+func testNextRound(t *testing.T, router *mux.Router, sessionID string) {
+	endpoint := fmt.Sprintf("/sessions/%s/rounds/next", sessionID)
+	req := httptest.NewRequest("POST", endpoint, nil)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var session review.Session
+	err := json.NewDecoder(rec.Body).Decode(&session)
+	require.NoError(t, err)
+	require.True(t, session.IsNewRound)
+	require.Zero(t, session.ReviewCount)
+}